@@ -0,0 +1,207 @@
+package bmstruct
+
+import "fmt"
+
+//Accessor is a precompiled handle of a Field of a Template. It caches the
+//location, the length and the Kind of the field, so accessing the field through
+//an Accessor neither looks up the field name nor allocates memory.
+//
+//An Accessor can be used with any Struct of the Template that created it.
+//Using it with a Struct of a different Template results in undefined behavior.
+type Accessor struct {
+	name           string
+	offset         uint64
+	length         uint64
+	bitFieldOffset uint8
+	bitFieldLen    uint8
+	kind           Kind
+}
+
+//Accessor method returns an Accessor for the field indicated by fieldName.
+//
+//Accessor panics for a non-existing field name, for a field whose position
+//depends on a preceding dynamic field or for a big-endian field, since the
+//methods of Accessor use the little-endian conversions of Value.
+func (t *Template) Accessor(fieldName string) *Accessor {
+	field := t.fixedField(fieldName)
+	if field.BigEndian {
+		panic(fmt.Sprintf("field %s is big-endian", fieldName))
+	}
+	return &Accessor{
+		name:           field.Name,
+		offset:         field.Offset,
+		length:         field.Len,
+		bitFieldOffset: field.BitFieldOffset,
+		bitFieldLen:    field.BitFieldLen,
+		kind:           field.Kind,
+	}
+}
+
+//Name method returns the name of the field.
+func (a *Accessor) Name() string {
+	return a.name
+}
+
+//Kind method returns the Kind of the field.
+func (a *Accessor) Kind() Kind {
+	return a.kind
+}
+
+//Len method returns the length of the field in bytes.
+func (a *Accessor) Len() uint64 {
+	return a.length
+}
+
+// slice returns the field in the data of s without copying it after checking
+// that the field has the given size.
+func (a *Accessor) slice(s *Struct, size int, typeName string) Value {
+	if a.bitFieldLen != 0 || a.length != uint64(size) {
+		panic("field " + a.name + " cannot be accessed as " + typeName)
+	}
+	return s.Value[a.offset : a.offset+uint64(size)]
+}
+
+//Value method returns the field of s. The returned Value refers to the data of
+//s, so modifying it modifies s. For bit fields a new Value is returned.
+func (a *Accessor) Value(s *Struct) Value {
+	if a.bitFieldLen != 0 {
+		return Value{bitFieldOfByte(s.Value[a.offset], a.bitFieldOffset, a.bitFieldLen)}
+	}
+	return s.Value[a.offset : a.offset+a.length]
+}
+
+//Set method changes the field of s to the given Value. It panics if the size
+//of the Value is incorrect.
+func (a *Accessor) Set(s *Struct, valuable Valuable) {
+	value := valuable.GetValue()
+	if a.bitFieldLen != 0 {
+		if len(value) != 1 {
+			panic("BitField value shall contain a single byte only")
+		}
+		setBitFieldOfByte(&s.Value[a.offset], a.bitFieldOffset, a.bitFieldLen, value[0])
+		return
+	}
+	if uint64(len(value)) != a.length {
+		panic("input value has incorrect length")
+	}
+	copy(s.Value[a.offset:a.offset+a.length], value)
+}
+
+//Uint8 method returns the uint8 field of s. The typed methods of Accessor
+//panic if the length of the field does not match the size of the type. Bit
+//fields can be accessed with the Uint8 and SetUint8 methods.
+func (a *Accessor) Uint8(s *Struct) uint8 {
+	if a.bitFieldLen != 0 {
+		return bitFieldOfByte(s.Value[a.offset], a.bitFieldOffset, a.bitFieldLen)
+	}
+	return a.slice(s, 1, "Uint8").Uint8()
+}
+
+//SetUint8 method changes the uint8 field of s to v.
+func (a *Accessor) SetUint8(s *Struct, v uint8) {
+	if a.bitFieldLen != 0 {
+		setBitFieldOfByte(&s.Value[a.offset], a.bitFieldOffset, a.bitFieldLen, v)
+		return
+	}
+	PutUint8(a.slice(s, 1, "Uint8"), v)
+}
+
+//Int8 method returns the int8 field of s.
+func (a *Accessor) Int8(s *Struct) int8 {
+	return a.slice(s, 1, "Int8").Int8()
+}
+
+//SetInt8 method changes the int8 field of s to v.
+func (a *Accessor) SetInt8(s *Struct, v int8) {
+	PutInt8(a.slice(s, 1, "Int8"), v)
+}
+
+//Uint16 method returns the uint16 field of s.
+func (a *Accessor) Uint16(s *Struct) uint16 {
+	return a.slice(s, 2, "Uint16").Uint16()
+}
+
+//SetUint16 method changes the uint16 field of s to v.
+func (a *Accessor) SetUint16(s *Struct, v uint16) {
+	PutUint16(a.slice(s, 2, "Uint16"), v)
+}
+
+//Int16 method returns the int16 field of s.
+func (a *Accessor) Int16(s *Struct) int16 {
+	return a.slice(s, 2, "Int16").Int16()
+}
+
+//SetInt16 method changes the int16 field of s to v.
+func (a *Accessor) SetInt16(s *Struct, v int16) {
+	PutInt16(a.slice(s, 2, "Int16"), v)
+}
+
+//Uint32 method returns the uint32 field of s.
+func (a *Accessor) Uint32(s *Struct) uint32 {
+	return a.slice(s, 4, "Uint32").Uint32()
+}
+
+//SetUint32 method changes the uint32 field of s to v.
+func (a *Accessor) SetUint32(s *Struct, v uint32) {
+	PutUint32(a.slice(s, 4, "Uint32"), v)
+}
+
+//Int32 method returns the int32 field of s.
+func (a *Accessor) Int32(s *Struct) int32 {
+	return a.slice(s, 4, "Int32").Int32()
+}
+
+//SetInt32 method changes the int32 field of s to v.
+func (a *Accessor) SetInt32(s *Struct, v int32) {
+	PutInt32(a.slice(s, 4, "Int32"), v)
+}
+
+//Uint64 method returns the uint64 field of s.
+func (a *Accessor) Uint64(s *Struct) uint64 {
+	return a.slice(s, 8, "Uint64").Uint64()
+}
+
+//SetUint64 method changes the uint64 field of s to v.
+func (a *Accessor) SetUint64(s *Struct, v uint64) {
+	PutUint64(a.slice(s, 8, "Uint64"), v)
+}
+
+//Int64 method returns the int64 field of s.
+func (a *Accessor) Int64(s *Struct) int64 {
+	return a.slice(s, 8, "Int64").Int64()
+}
+
+//SetInt64 method changes the int64 field of s to v.
+func (a *Accessor) SetInt64(s *Struct, v int64) {
+	PutInt64(a.slice(s, 8, "Int64"), v)
+}
+
+//Uintptr method returns the uintptr field of s.
+func (a *Accessor) Uintptr(s *Struct) uintptr {
+	return a.slice(s, uintptrSize, "Uintptr").Uintptr()
+}
+
+//SetUintptr method changes the uintptr field of s to v.
+func (a *Accessor) SetUintptr(s *Struct, v uintptr) {
+	PutUintptr(a.slice(s, uintptrSize, "Uintptr"), v)
+}
+
+//Float32 method returns the float32 field of s.
+func (a *Accessor) Float32(s *Struct) float32 {
+	return a.slice(s, 4, "Float32").Float32()
+}
+
+//SetFloat32 method changes the float32 field of s to v.
+func (a *Accessor) SetFloat32(s *Struct, v float32) {
+	PutFloat32(a.slice(s, 4, "Float32"), v)
+}
+
+//Float64 method returns the float64 field of s.
+func (a *Accessor) Float64(s *Struct) float64 {
+	return a.slice(s, 8, "Float64").Float64()
+}
+
+//SetFloat64 method changes the float64 field of s to v.
+func (a *Accessor) SetFloat64(s *Struct, v float64) {
+	PutFloat64(a.slice(s, 8, "Float64"), v)
+}
@@ -0,0 +1,78 @@
+package bmstruct
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Accessor", func() {
+	var tmpl *Template
+	var s1, s2 *Struct
+
+	BeforeEach(func() {
+		tmpl = NewTemplate(16,
+			Uint8Field("u8", 0),
+			BitField("bf", 1, 2, 3),
+			Int16Field("i16", 2),
+			Uint32Field("u32", 4),
+			Int64Field("i64", 8),
+		)
+		s1 = tmpl.Empty()
+		s2 = tmpl.New(Value{
+			1, 0x0c, 0xfe, 0xff, 4, 3, 2, 1,
+			0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		})
+	})
+	It("should describe the field", func() {
+		acc := tmpl.Accessor("u32")
+		Expect(acc.Name()).To(Equal("u32"))
+		Expect(acc.Kind()).To(Equal(Uint32Kind))
+		Expect(acc.Len()).To(Equal(uint64(4)))
+	})
+	It("should panic for non-existing field", func() {
+		Expect(func() {
+			tmpl.Accessor("no-such-field")
+		}).To(Panic())
+	})
+	It("should read the fields of any Struct", func() {
+		u32 := tmpl.Accessor("u32")
+		Expect(u32.Uint32(s1)).To(Equal(uint32(0)))
+		Expect(u32.Uint32(s2)).To(Equal(uint32(0x01020304)))
+		Expect(tmpl.Accessor("u8").Uint8(s2)).To(Equal(uint8(1)))
+		Expect(tmpl.Accessor("bf").Uint8(s2)).To(Equal(uint8(3)))
+		Expect(tmpl.Accessor("i16").Int16(s2)).To(Equal(int16(-2)))
+		Expect(tmpl.Accessor("i64").Int64(s2)).To(Equal(int64(-2)))
+		Expect(tmpl.Accessor("i16").Value(s2)).To(Equal(Value{0xfe, 0xff}))
+		Expect(tmpl.Accessor("bf").Value(s2)).To(Equal(Value{3}))
+	})
+	It("should change the fields of any Struct", func() {
+		tmpl.Accessor("u32").SetUint32(s1, 0x01020304)
+		tmpl.Accessor("bf").SetUint8(s1, 5)
+		tmpl.Accessor("i16").SetInt16(s1, -2)
+		tmpl.Accessor("u8").Set(s1, Uint8(7))
+		tmpl.Accessor("i64").SetInt64(s2, 42)
+		Expect(s1.Value[0:8]).To(Equal(Value{7, 0x14, 0xfe, 0xff, 4, 3, 2, 1}))
+		Expect(s2.Lookup("i64").Int64()).To(Equal(int64(42)))
+	})
+	It("should panic for incorrect types", func() {
+		Expect(func() {
+			tmpl.Accessor("u32").Uint16(s1)
+		}).To(Panic())
+		Expect(func() {
+			tmpl.Accessor("bf").SetUint16(s1, 1)
+		}).To(Panic())
+		Expect(func() {
+			tmpl.Accessor("i16").Set(s1, Uint32(1))
+		}).To(Panic())
+	})
+	It("should not allocate", func() {
+		u32 := tmpl.Accessor("u32")
+		bf := tmpl.Accessor("bf")
+		Expect(testing.AllocsPerRun(100, func() {
+			u32.SetUint32(s1, u32.Uint32(s2)+1)
+			bf.SetUint8(s1, bf.Uint8(s2))
+		})).To(BeZero())
+	})
+})
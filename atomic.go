@@ -0,0 +1,147 @@
+package bmstruct
+
+import (
+	"fmt"
+	"sync/atomic"
+	"unsafe"
+)
+
+// nativeLittleEndian is true if the byte order of the host is little-endian,
+// i.e. the same as the byte order of the Values.
+var nativeLittleEndian = func() bool {
+	i := uint16(1)
+	return *(*byte)(unsafe.Pointer(&i)) == 1
+}()
+
+// atomicPointer returns the pointer to the field indicated by fieldName after
+// checking that the field can be accessed atomically as an integer of the
+// given size.
+func (s *Struct) atomicPointer(fieldName string, size uint64) unsafe.Pointer {
+	field := s.resolve(fieldName).field
+	if !nativeLittleEndian {
+		panic("atomic operations require a little-endian host")
+	}
+	if field.BitFieldLen != 0 || field.BigEndian || field.Len != size {
+		panic(fmt.Sprintf("field %s (%d bytes) cannot be accessed atomically as %d bytes",
+			fieldName, field.Len, size))
+	}
+	address := s.Value.Address() + uintptr(field.Offset)
+	if address%uintptr(size) != 0 {
+		panic(fmt.Sprintf("field %s at address 0x%x is not aligned to %d bytes",
+			fieldName, address, size))
+	}
+	return unsafe.Pointer(&s.Value[field.Offset])
+}
+
+//AtomicLoadUint32 method atomically loads the 4 bytes long field indicated by
+//fieldName.
+//
+//The atomic methods of Struct panic for a non-existing field name, for a field
+//of incorrect length, for a big-endian field, for a field that is not aligned
+//in memory to its length and on big-endian hosts.
+func (s *Struct) AtomicLoadUint32(fieldName string) uint32 {
+	return atomic.LoadUint32((*uint32)(s.atomicPointer(fieldName, 4)))
+}
+
+//AtomicLoadUint64 method atomically loads the 8 bytes long field indicated by
+//fieldName.
+func (s *Struct) AtomicLoadUint64(fieldName string) uint64 {
+	return atomic.LoadUint64((*uint64)(s.atomicPointer(fieldName, 8)))
+}
+
+//AtomicStoreUint32 method atomically stores v into the 4 bytes long field
+//indicated by fieldName.
+func (s *Struct) AtomicStoreUint32(fieldName string, v uint32) {
+	atomic.StoreUint32((*uint32)(s.atomicPointer(fieldName, 4)), v)
+}
+
+//AtomicStoreUint64 method atomically stores v into the 8 bytes long field
+//indicated by fieldName.
+func (s *Struct) AtomicStoreUint64(fieldName string, v uint64) {
+	atomic.StoreUint64((*uint64)(s.atomicPointer(fieldName, 8)), v)
+}
+
+//AtomicAddUint32 method atomically adds delta to the 4 bytes long field
+//indicated by fieldName and returns the new value.
+func (s *Struct) AtomicAddUint32(fieldName string, delta uint32) uint32 {
+	return atomic.AddUint32((*uint32)(s.atomicPointer(fieldName, 4)), delta)
+}
+
+//AtomicAddUint64 method atomically adds delta to the 8 bytes long field
+//indicated by fieldName and returns the new value.
+func (s *Struct) AtomicAddUint64(fieldName string, delta uint64) uint64 {
+	return atomic.AddUint64((*uint64)(s.atomicPointer(fieldName, 8)), delta)
+}
+
+//AtomicCompareAndSwapUint32 method atomically changes the 4 bytes long field
+//indicated by fieldName to new if its current value is old. It returns true if
+//the field was changed.
+func (s *Struct) AtomicCompareAndSwapUint32(fieldName string, old, new uint32) bool {
+	return atomic.CompareAndSwapUint32((*uint32)(s.atomicPointer(fieldName, 4)), old, new)
+}
+
+//AtomicCompareAndSwapUint64 method atomically changes the 8 bytes long field
+//indicated by fieldName to new if its current value is old. It returns true if
+//the field was changed.
+func (s *Struct) AtomicCompareAndSwapUint64(fieldName string, old, new uint64) bool {
+	return atomic.CompareAndSwapUint64((*uint64)(s.atomicPointer(fieldName, 8)), old, new)
+}
+
+// atomicBitField returns the aligned 32 bit word that contains the bit field
+// indicated by fieldName and the shift of the bit field inside the word.
+func (s *Struct) atomicBitField(fieldName string) (*uint32, *Field, uint) {
+	field := s.resolve(fieldName).field
+	if !nativeLittleEndian {
+		panic("atomic operations require a little-endian host")
+	}
+	if field.BitFieldLen == 0 {
+		panic(fmt.Sprintf("field %s is not a bit field", fieldName))
+	}
+	address := s.Value.Address() + uintptr(field.Offset)
+	byteShift := uint64(address % 4)
+	if byteShift > field.Offset || field.Offset-byteShift+4 > uint64(len(s.Value)) {
+		panic(fmt.Sprintf("the aligned word of bit field %s exceeds the data", fieldName))
+	}
+	word := (*uint32)(unsafe.Pointer(&s.Value[field.Offset-byteShift]))
+	return word, field, uint(byteShift*8) + uint(field.BitFieldOffset)
+}
+
+//AtomicLoadBitField method atomically loads the bit field indicated by
+//fieldName.
+//
+//The atomic bit field methods access the aligned 4 bytes long word containing
+//the bit field, so this word shall be inside the data of the Struct. They panic
+//for a non-existing field name, for a field that is not a bit field and on
+//big-endian hosts.
+func (s *Struct) AtomicLoadBitField(fieldName string) uint8 {
+	word, field, shift := s.atomicBitField(fieldName)
+	mask := uint32(1)<<field.BitFieldLen - 1
+	return uint8(atomic.LoadUint32(word) >> shift & mask)
+}
+
+//AtomicStoreBitField method atomically changes the bit field indicated by
+//fieldName to v without modifying the neighbouring bits. It returns the
+//previous value of the bit field.
+func (s *Struct) AtomicStoreBitField(fieldName string, v uint8) uint8 {
+	word, field, shift := s.atomicBitField(fieldName)
+	mask := uint32(1)<<field.BitFieldLen - 1
+	for {
+		old := atomic.LoadUint32(word)
+		new := old&^(mask<<shift) | (uint32(v)&mask)<<shift
+		if atomic.CompareAndSwapUint32(word, old, new) {
+			return uint8(old >> shift & mask)
+		}
+	}
+}
+
+//AtomicSetBitField method atomically sets all bits of the bit field indicated
+//by fieldName to 1. It returns the previous value of the bit field.
+func (s *Struct) AtomicSetBitField(fieldName string) uint8 {
+	return s.AtomicStoreBitField(fieldName, 0xff)
+}
+
+//AtomicClearBitField method atomically sets all bits of the bit field
+//indicated by fieldName to 0. It returns the previous value of the bit field.
+func (s *Struct) AtomicClearBitField(fieldName string) uint8 {
+	return s.AtomicStoreBitField(fieldName, 0)
+}
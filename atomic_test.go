@@ -0,0 +1,115 @@
+package bmstruct
+
+import (
+	"sync"
+	"unsafe"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// alignedValue returns a zeroed Value of n*8 bytes that is aligned to 8 bytes.
+func alignedValue(n int) Value {
+	words := make([]uint64, n)
+	return (*[1 << 20]byte)(unsafe.Pointer(&words[0]))[: n*8 : n*8]
+}
+
+var _ = Describe("Atomic", func() {
+	var tmpl *Template
+	var s *Struct
+
+	BeforeEach(func() {
+		tmpl = NewTemplate(16,
+			Uint32Field("u32", 0),
+			Uint32Field("unaligned", 1),
+			BitField("flag0", 4, 0, 1),
+			BitField("flag1", 4, 1, 1),
+			BitField("nibble", 5, 4, 4),
+			Uint16Field("u16", 6),
+			Uint64Field("u64", 8),
+		)
+		s = tmpl.New(alignedValue(2))
+	})
+	Describe("for 32 and 64 bit fields", func() {
+		It("should load and store", func() {
+			s.AtomicStoreUint32("u32", 0x01020304)
+			s.AtomicStoreUint64("u64", 0x0102030405060708)
+			Expect(s.Lookup("u32").Uint32()).To(Equal(uint32(0x01020304)))
+			Expect(s.AtomicLoadUint32("u32")).To(Equal(uint32(0x01020304)))
+			Expect(s.AtomicLoadUint64("u64")).To(Equal(uint64(0x0102030405060708)))
+		})
+		It("should compare and swap", func() {
+			s.AtomicStoreUint64("u64", 1)
+			Expect(s.AtomicCompareAndSwapUint64("u64", 2, 3)).To(BeFalse())
+			Expect(s.AtomicCompareAndSwapUint64("u64", 1, 3)).To(BeTrue())
+			Expect(s.AtomicCompareAndSwapUint32("u32", 0, 5)).To(BeTrue())
+			Expect(s.Lookup("u64").Uint64()).To(Equal(uint64(3)))
+			Expect(s.Lookup("u32").Uint32()).To(Equal(uint32(5)))
+		})
+		It("should add concurrently", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 1000; j++ {
+						s.AtomicAddUint32("u32", 1)
+						s.AtomicAddUint64("u64", 2)
+					}
+				}()
+			}
+			wg.Wait()
+			Expect(s.AtomicLoadUint32("u32")).To(Equal(uint32(8000)))
+			Expect(s.AtomicLoadUint64("u64")).To(Equal(uint64(16000)))
+		})
+		It("should panic for incorrect field length", func() {
+			Expect(func() {
+				s.AtomicLoadUint32("u16")
+			}).To(Panic())
+			Expect(func() {
+				s.AtomicLoadUint64("u32")
+			}).To(Panic())
+			Expect(func() {
+				s.AtomicLoadUint32("flag0")
+			}).To(Panic())
+		})
+		It("should panic for unaligned field", func() {
+			Expect(func() {
+				s.AtomicStoreUint32("unaligned", 1)
+			}).To(Panic())
+		})
+	})
+	Describe("for bit fields", func() {
+		It("should set and clear the bits", func() {
+			s.Update("u16", Uint16(0xffff))
+			Expect(s.AtomicSetBitField("nibble")).To(Equal(uint8(0)))
+			Expect(s.AtomicStoreBitField("nibble", 5)).To(Equal(uint8(15)))
+			Expect(s.AtomicSetBitField("flag1")).To(Equal(uint8(0)))
+			Expect(s.Value[4:8]).To(Equal(Value{0x02, 0x50, 0xff, 0xff}))
+			Expect(s.AtomicClearBitField("flag1")).To(Equal(uint8(1)))
+			Expect(s.AtomicLoadBitField("nibble")).To(Equal(uint8(5)))
+			Expect(s.Value[4:8]).To(Equal(Value{0x00, 0x50, 0xff, 0xff}))
+		})
+		It("should not lose concurrent updates of neighbouring bits", func() {
+			var wg sync.WaitGroup
+			for _, name := range []string{"flag0", "flag1"} {
+				wg.Add(1)
+				go func(name string) {
+					defer wg.Done()
+					for j := 0; j < 1000; j++ {
+						s.AtomicClearBitField(name)
+						s.AtomicSetBitField(name)
+					}
+				}(name)
+			}
+			wg.Wait()
+			Expect(s.Lookup("flag0").Uint8()).To(Equal(uint8(1)))
+			Expect(s.Lookup("flag1").Uint8()).To(Equal(uint8(1)))
+		})
+		It("should panic for non bit fields", func() {
+			Expect(func() {
+				s.AtomicSetBitField("u32")
+			}).To(Panic())
+		})
+	})
+})
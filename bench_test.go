@@ -0,0 +1,144 @@
+package bmstruct
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+var benchSinkUint32 uint32
+var benchSinkUint64 uint64
+
+func BenchmarkValueUint32(b *testing.B) {
+	v := Uint32(0x01020304)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchSinkUint32 = v.Uint32()
+	}
+}
+
+func BenchmarkBinaryUint32(b *testing.B) {
+	v := []byte{4, 3, 2, 1}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchSinkUint32 = binary.LittleEndian.Uint32(v)
+	}
+}
+
+func BenchmarkPutUint32(b *testing.B) {
+	v := make(Value, 4)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		PutUint32(v, uint32(i))
+	}
+}
+
+func BenchmarkBinaryPutUint32(b *testing.B) {
+	v := make([]byte, 4)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		binary.LittleEndian.PutUint32(v, uint32(i))
+	}
+}
+
+func BenchmarkValueUint64(b *testing.B) {
+	v := Uint64(0x0102030405060708)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchSinkUint64 = v.Uint64()
+	}
+}
+
+func BenchmarkBinaryUint64(b *testing.B) {
+	v := []byte{8, 7, 6, 5, 4, 3, 2, 1}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchSinkUint64 = binary.LittleEndian.Uint64(v)
+	}
+}
+
+func BenchmarkPutUint64(b *testing.B) {
+	v := make(Value, 8)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		PutUint64(v, uint64(i))
+	}
+}
+
+func BenchmarkBinaryPutUint64(b *testing.B) {
+	v := make([]byte, 8)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		binary.LittleEndian.PutUint64(v, uint64(i))
+	}
+}
+
+func BenchmarkNewUint32(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchSinkUint32 = Uint32(uint32(i))[0:4].Uint32()
+	}
+}
+
+func BenchmarkStructLookupUint32(b *testing.B) {
+	s := NewTemplate(8, Uint32Field("f", 4)).Empty()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchSinkUint32 = s.Lookup("f").Uint32()
+	}
+}
+
+func BenchmarkStructsColumnUint32(b *testing.B) {
+	ss := NewTemplate(8, Uint32Field("f", 4)).Slice(make(Value, 8*1024))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchSinkUint32 = ss.Column("f").([]uint32)[0]
+	}
+}
+
+func BenchmarkAccessorUint32(b *testing.B) {
+	t := NewTemplate(8, Uint32Field("f", 4))
+	s := t.Empty()
+	acc := t.Accessor("f")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchSinkUint32 = acc.Uint32(s)
+	}
+}
+
+func BenchmarkAccessorSetUint32(b *testing.B) {
+	t := NewTemplate(8, Uint32Field("f", 4))
+	s := t.Empty()
+	acc := t.Accessor("f")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		acc.SetUint32(s, uint32(i))
+	}
+}
+
+func BenchmarkDynamicStructLookupUint32(b *testing.B) {
+	s := NewTemplate(-1,
+		Uint32Field("f", 0),
+		Uint8Field("len", 4),
+		DynamicField("data", 5, "len"),
+		Uint32Field("tail", 5),
+	).Empty()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchSinkUint32 = s.Lookup("f").Uint32()
+	}
+}
+
+func BenchmarkDynamicStructLookupShiftedUint32(b *testing.B) {
+	s := NewTemplate(-1,
+		Uint8Field("len", 0),
+		DynamicField("data", 1, "len"),
+		Uint32Field("tail", 1),
+	).Empty()
+	if err := s.Set("data", Value{1, 2, 3}); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchSinkUint32 = s.Lookup("tail").Uint32()
+	}
+}
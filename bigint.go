@@ -0,0 +1,264 @@
+package bmstruct
+
+import (
+	"fmt"
+	"math/big"
+)
+
+//Uint128 is an unsigned 128-bit integer represented by two uint64 values, e.g.
+//an IPv6 address or a UUID. It is a cheaper alternative of big.Int for the
+//fields of Uint128Kind.
+type Uint128 struct {
+	Hi uint64
+	Lo uint64
+}
+
+//GetValue method converts the Uint128 to a 16 bytes long little-endian Value,
+//so a Uint128 is a Valuable.
+func (u Uint128) GetValue() Value {
+	b := make(Value, 16)
+	PutUint128(b, u)
+	return b
+}
+
+//Big method returns the Uint128 as a big.Int.
+func (u Uint128) Big() *big.Int {
+	x := new(big.Int).SetUint64(u.Hi)
+	x.Lsh(x, 64)
+	return x.Or(x, new(big.Int).SetUint64(u.Lo))
+}
+
+//String method returns the Uint128 as a decimal number.
+func (u Uint128) String() string {
+	return u.Big().String()
+}
+
+//MarshalText implements the encoding.TextMarshaler interface, so a Uint128 is
+//represented as a decimal number in a JSON string.
+func (u Uint128) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+//UnmarshalText implements the encoding.TextUnmarshaler interface. It accepts
+//the numbers in the syntax of Go integer literals, e.g. "0x20010db8...".
+func (u *Uint128) UnmarshalText(text []byte) error {
+	x, ok := new(big.Int).SetString(string(text), 0)
+	if !ok {
+		return fmt.Errorf("invalid 128-bit integer %q", text)
+	}
+	v, err := uint128Of(x)
+	if err != nil {
+		return err
+	}
+	*u = v
+	return nil
+}
+
+// uint128Of converts x to Uint128. It returns an error wrapping ErrOverflow if x
+// is negative or longer than 128 bits.
+func uint128Of(x *big.Int) (Uint128, error) {
+	if x.Sign() < 0 || x.BitLen() > 128 {
+		return Uint128{}, fmt.Errorf("%s: %w", x, ErrOverflow)
+	}
+	lo := new(big.Int).And(x, new(big.Int).SetUint64(^uint64(0)))
+	return Uint128{
+		Hi: new(big.Int).Rsh(x, 64).Uint64(),
+		Lo: lo.Uint64(),
+	}, nil
+}
+
+//PutUint128 function writes v into dst without allocating a new Value. The
+//function will panic if the length of dst is incorrect.
+func PutUint128(dst Value, v Uint128) {
+	dst.checkLen(16, "Uint128")
+	putUintBytes(dst[:8], v.Lo)
+	putUintBytes(dst[8:], v.Hi)
+}
+
+//Uint128 method returns the Uint128 representation of a Value. The method will
+//panic if the Value's length is incorrect.
+func (v Value) Uint128() Uint128 {
+	v.checkLen(16, "Uint128")
+	return Uint128{
+		Hi: uintOfBytes(v[8:]),
+		Lo: uintOfBytes(v[:8]),
+	}
+}
+
+//BigUint function converts a non-negative big.Int to a little-endian Value of n
+//bytes. The function will panic if v is negative or does not fit into n bytes.
+func BigUint(v *big.Int, n int) Value {
+	b, err := bigBytes(v, n, false)
+	if err != nil {
+		panic(err.Error())
+	}
+	return b
+}
+
+//BigInt function converts a big.Int to a little-endian two's complement Value
+//of n bytes. The function will panic if v does not fit into n bytes.
+func BigInt(v *big.Int, n int) Value {
+	b, err := bigBytes(v, n, true)
+	if err != nil {
+		panic(err.Error())
+	}
+	return b
+}
+
+//BigUint method returns the Value as an unsigned little-endian integer of any
+//length.
+func (v Value) BigUint() *big.Int {
+	be := make([]byte, len(v))
+	for i, c := range v {
+		be[len(v)-1-i] = c
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+//BigInt method returns the Value as a little-endian two's complement integer
+//of any length, i.e. the Value is negative if its most significant bit is set.
+func (v Value) BigInt() *big.Int {
+	x := v.BigUint()
+	if len(v) > 0 && v[len(v)-1]&0x80 != 0 {
+		x.Sub(x, new(big.Int).Lsh(big.NewInt(1), uint(8*len(v))))
+	}
+	return x
+}
+
+// bigBytes returns v as a little-endian Value of n bytes. It returns an error
+// wrapping ErrOverflow if v does not fit.
+func bigBytes(v *big.Int, n int, signed bool) (Value, error) {
+	bits := 8 * n
+	x := v
+	switch {
+	case !signed && (v.Sign() < 0 || v.BitLen() > bits):
+		return nil, fmt.Errorf("%s does not fit into %d bytes: %w", v, n, ErrOverflow)
+	case signed && v.Sign() >= 0 && v.BitLen() > bits-1:
+		return nil, fmt.Errorf("%s does not fit into %d bytes: %w", v, n, ErrOverflow)
+	case signed && v.Sign() < 0:
+		// The two's complement of v is 2^bits + v, which fits if v >= -2^(bits-1).
+		x = new(big.Int).Add(v, new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+		if x.Sign() < 0 || x.BitLen() < bits {
+			return nil, fmt.Errorf("%s does not fit into %d bytes: %w", v, n, ErrOverflow)
+		}
+	}
+	be := x.Bytes()
+	b := make(Value, n)
+	for i, c := range be {
+		b[len(be)-1-i] = c
+	}
+	return b, nil
+}
+
+//Uint128Field creates a new Field of Uint128Kind with the given name and
+//offset that stores an unsigned little-endian 128-bit integer. The Get method
+//of Struct returns a Uint128.
+//
+//Integers in network byte order, e.g. IPv6 addresses, are stored big-endian,
+//which the BigEndian option of the Field selects:
+//
+//  addr := Uint128Field("src", 8)
+//  addr.BigEndian = true
+//
+//The BigEndian option applies to BigUintField and BigIntField too.
+func Uint128Field(name string, offset uint64) *Field {
+	return &Field{
+		Name:   name,
+		Offset: offset,
+		Len:    16,
+		Kind:   Uint128Kind,
+	}
+}
+
+//BigUintField creates a new Field of BigUintKind with the given name and offset
+//that stores an unsigned little-endian integer of the given number of bytes,
+//e.g. 32 for a 256-bit integer. The Get method of Struct returns a *big.Int.
+//
+//BigUintField panics if bytes is 0.
+func BigUintField(name string, offset, bytes uint64) *Field {
+	if bytes == 0 {
+		panic("invalid big integer: length cannot be 0")
+	}
+	return &Field{
+		Name:   name,
+		Offset: offset,
+		Len:    bytes,
+		Kind:   BigUintKind,
+	}
+}
+
+//BigIntField creates a new Field of BigIntKind with the given name and offset
+//that stores a signed little-endian two's complement integer of the given
+//number of bytes. The Get method of Struct returns a *big.Int.
+//
+//BigIntField panics if bytes is 0.
+func BigIntField(name string, offset, bytes uint64) *Field {
+	if bytes == 0 {
+		panic("invalid big integer: length cannot be 0")
+	}
+	return &Field{
+		Name:   name,
+		Offset: offset,
+		Len:    bytes,
+		Kind:   BigIntKind,
+	}
+}
+
+// bigOf returns the value of the Uint128, BigUint or BigInt field as big.Int.
+func (f *Field) bigOf(data []byte) *big.Int {
+	if f.Kind == BigIntKind {
+		return f.littleEndian(data).BigInt()
+	}
+	return f.littleEndian(data).BigUint()
+}
+
+// uint128Of returns the value of the Uint128 field.
+func (f *Field) uint128Of(data []byte) Uint128 {
+	return f.littleEndian(data).Uint128()
+}
+
+// littleEndian returns the content of the Uint128, BigUint or BigInt field in
+// little-endian byte order. The returned Value is a copy only if the field is
+// big-endian.
+func (f *Field) littleEndian(data []byte) Value {
+	if f.BigEndian {
+		return reversed(f.slice(data))
+	}
+	return f.slice(data)
+}
+
+// reversed returns a copy of b in reverse byte order.
+func reversed(b []byte) Value {
+	r := make(Value, len(b))
+	for i, c := range b {
+		r[len(b)-1-i] = c
+	}
+	return r
+}
+
+// encodeBig converts v to the Value of the Uint128, BigUint or BigInt field.
+// Besides *big.Int, v may be a Uint128 or a Go integer.
+func (f *Field) encodeBig(v interface{}) (Value, error) {
+	var x *big.Int
+	switch y := v.(type) {
+	case *big.Int:
+		x = y
+	case Uint128:
+		x = y.Big()
+	default:
+		abs, negative, ok := integerOf(v)
+		if !ok {
+			return nil, fmt.Errorf("value of type %T cannot be stored in a field of kind %s",
+				v, f.Kind)
+		}
+		x = new(big.Int).SetUint64(abs)
+		if negative {
+			x.Neg(x)
+		}
+	}
+	b, err := bigBytes(x, int(f.Len), f.Kind == BigIntKind)
+	if err != nil || !f.BigEndian {
+		return b, err
+	}
+	return reversed(b), nil
+}
@@ -0,0 +1,148 @@
+package bmstruct
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Big integers", func() {
+	parse := func(s string) *big.Int {
+		x, ok := new(big.Int).SetString(s, 0)
+		Expect(ok).To(BeTrue())
+		return x
+	}
+
+	Describe("Uint128", func() {
+		It("should convert to and from Value", func() {
+			u := Uint128{Hi: 0x0102030405060708, Lo: 0x090a0b0c0d0e0f10}
+			v := u.GetValue()
+			Expect(v).To(Equal(Value{
+				0x10, 0x0f, 0x0e, 0x0d, 0x0c, 0x0b, 0x0a, 0x09,
+				0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01,
+			}))
+			Expect(v.Uint128()).To(Equal(u))
+			Expect(u.Big()).To(Equal(parse("0x0102030405060708090a0b0c0d0e0f10")))
+			Expect(func() { Value{1}.Uint128() }).To(Panic())
+		})
+		It("should be represented as a decimal string in JSON", func() {
+			b, err := json.Marshal(Uint128{Hi: 1})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(b)).To(Equal(`"18446744073709551616"`))
+			var u Uint128
+			Expect(json.Unmarshal([]byte(`"0xffffffffffffffffffffffffffffffff"`), &u)).To(Succeed())
+			Expect(u).To(Equal(Uint128{Hi: ^uint64(0), Lo: ^uint64(0)}))
+			Expect(json.Unmarshal([]byte(`"0x100000000000000000000000000000000"`), &u)).NotTo(Succeed())
+			Expect(json.Unmarshal([]byte(`"-1"`), &u)).NotTo(Succeed())
+		})
+	})
+	Describe("Value conversions", func() {
+		It("should convert unsigned integers", func() {
+			Expect(BigUint(big.NewInt(0x0102), 3)).To(Equal(Value{0x02, 0x01, 0x00}))
+			Expect(Value{0x02, 0x01, 0x00}.BigUint()).To(Equal(big.NewInt(0x0102)))
+			Expect(Value{0xff}.BigUint()).To(Equal(big.NewInt(255)))
+			Expect(func() { BigUint(big.NewInt(-1), 4) }).To(Panic())
+			Expect(func() { BigUint(big.NewInt(256), 1) }).To(Panic())
+		})
+		It("should handle the sign", func() {
+			Expect(BigInt(big.NewInt(-2), 3)).To(Equal(Value{0xfe, 0xff, 0xff}))
+			Expect(Value{0xfe, 0xff, 0xff}.BigInt()).To(Equal(big.NewInt(-2)))
+			Expect(BigInt(big.NewInt(-128), 1)).To(Equal(Value{0x80}))
+			Expect(BigInt(big.NewInt(127), 1)).To(Equal(Value{0x7f}))
+			Expect(func() { BigInt(big.NewInt(128), 1) }).To(Panic())
+			Expect(func() { BigInt(big.NewInt(-129), 1) }).To(Panic())
+			Expect(func() { BigInt(big.NewInt(-100000), 1) }).To(Panic())
+		})
+	})
+	Describe("fields", func() {
+		var tmpl *Template
+		var s *Struct
+
+		BeforeEach(func() {
+			tmpl = NewTemplate(-1,
+				Uint128Field("addr", 0),
+				BigUintField("hash", 16, 32),
+				BigIntField("delta", 48, 20),
+			)
+			s = tmpl.Empty()
+		})
+		It("should read and write the integers", func() {
+			Expect(s.Set("addr", Uint128{Hi: 0x20010db8 << 32, Lo: 1})).To(Succeed())
+			Expect(s.Get("addr")).To(Equal(Uint128{Hi: 0x20010db8 << 32, Lo: 1}))
+			Expect(s.Set("addr", 42)).To(Succeed())
+			Expect(s.Get("addr")).To(Equal(Uint128{Lo: 42}))
+			hash := new(big.Int).Lsh(big.NewInt(1), 255)
+			Expect(s.Set("hash", hash)).To(Succeed())
+			Expect(s.Get("hash")).To(Equal(hash))
+			Expect(s.Lookup("hash")[31]).To(Equal(byte(0x80)))
+			Expect(s.Set("delta", -5)).To(Succeed())
+			Expect(s.Get("delta")).To(Equal(big.NewInt(-5)))
+			Expect(s.Set("delta", Uint128{Lo: 7})).To(Succeed())
+			Expect(s.Get("delta")).To(Equal(big.NewInt(7)))
+		})
+		It("should read and write big-endian integers", func() {
+			addr := Uint128Field("addr", 0)
+			addr.BigEndian = true
+			delta := BigIntField("delta", 16, 3)
+			delta.BigEndian = true
+			s := NewTemplate(-1, addr, delta).Empty()
+			Expect(s.Set("addr", Uint128{Hi: 0x20010db8 << 32, Lo: 1})).To(Succeed())
+			Expect(s.Lookup("addr")).To(Equal(Value{
+				0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0,
+				0, 0, 0, 0, 0, 0, 0, 1,
+			}))
+			Expect(s.Get("addr")).To(Equal(Uint128{Hi: 0x20010db8 << 32, Lo: 1}))
+			Expect(s.Set("delta", -2)).To(Succeed())
+			Expect(s.Lookup("delta")).To(Equal(Value{0xff, 0xff, 0xfe}))
+			Expect(s.Get("delta")).To(Equal(big.NewInt(-2)))
+			Expect(errors.Is(s.Set("delta", 1<<23), ErrOverflow)).To(BeTrue())
+		})
+		It("should check the range", func() {
+			Expect(errors.Is(s.Set("addr", -1), ErrOverflow)).To(BeTrue())
+			Expect(errors.Is(s.Set("hash", new(big.Int).Lsh(big.NewInt(1), 256)), ErrOverflow)).
+				To(BeTrue())
+			Expect(errors.Is(s.Set("delta", new(big.Int).Lsh(big.NewInt(1), 159)), ErrOverflow)).
+				To(BeTrue())
+			Expect(s.Set("delta", new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 159)))).
+				To(Succeed())
+			Expect(s.Set("hash", "1")).NotTo(Succeed())
+		})
+		It("should compare numerically", func() {
+			a, b := tmpl.Empty(), tmpl.Empty()
+			Expect(a.Set("delta", -1)).To(Succeed())
+			Expect(b.Set("delta", 1)).To(Succeed())
+			Expect(tmpl.Fields["delta"].compare(a.Value, b.Value)).To(Equal(-1))
+			Expect(a.Set("addr", Uint128{Hi: 1})).To(Succeed())
+			Expect(b.Set("addr", Uint128{Lo: ^uint64(0)})).To(Succeed())
+			Expect(tmpl.Fields["addr"].compare(a.Value, b.Value)).To(Equal(1))
+		})
+		It("should be represented as strings in the JSON of the Struct", func() {
+			Expect(s.Set("addr", Uint128{Hi: 1})).To(Succeed())
+			Expect(s.Set("hash", new(big.Int).Lsh(big.NewInt(1), 255))).To(Succeed())
+			Expect(s.Set("delta", -5)).To(Succeed())
+			b, err := json.Marshal(s)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(b)).To(ContainSubstring(`"fields":{"addr":"18446744073709551616",` +
+				`"hash":"57896044618658097711785492504343953926634992332820282019728792003956564819968",` +
+				`"delta":"-5"}`))
+			var decoded Struct
+			Expect(json.Unmarshal(b, &decoded)).To(Succeed())
+			Expect(decoded.Value).To(Equal(s.Value))
+			t, err := json.Marshal(tmpl)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(json.Unmarshal([]byte(`{"template":`+string(t)+
+				`,"fields":{"addr":"0x20010db8000000000000000000000001","delta":-7}}`), &decoded)).To(Succeed())
+			Expect(decoded.Get("addr")).To(Equal(Uint128{Hi: 0x20010db8 << 32, Lo: 1}))
+			Expect(decoded.Get("delta")).To(Equal(big.NewInt(-7)))
+			Expect(json.Unmarshal([]byte(`{"template":`+string(t)+`,"fields":{"addr":"x"}}`), &decoded)).
+				NotTo(Succeed())
+		})
+		It("should panic for empty fields", func() {
+			Expect(func() { BigUintField("f", 0, 0) }).To(Panic())
+			Expect(func() { BigIntField("f", 0, 0) }).To(Panic())
+		})
+	})
+})
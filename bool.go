@@ -0,0 +1,64 @@
+package bmstruct
+
+import "fmt"
+
+//BoolField function creates a new Field of BoolKind with the given name and
+//offset that stores a boolean in a single byte. False is stored as 0, true as
+//1. Any other value reads as true, unless the Strict option of the Field is
+//set, in which case it is invalid: the Get method of Struct returns it as a
+//Value and Struct.BoolE returns an error. A strict field is created like
+//
+//  valid := BoolField("valid", 4)
+//  valid.Strict = true
+//
+//In the expressions of Template.Compile, the field is 1 if it is true and 0
+//otherwise.
+func BoolField(name string, offset uint64) *Field {
+	return &Field{
+		Name:   name,
+		Offset: offset,
+		Len:    1,
+		Kind:   BoolKind,
+	}
+}
+
+//BoolBitField function creates a new bit field of BoolKind with the given name,
+//offset and bitFieldOffset that stores a boolean in a single bit. Like other
+//bit fields, it can indicate the presence of an optional field, see
+//OptionalField.
+func BoolBitField(name string, offset uint64, bitFieldOffset uint8) *Field {
+	f := BitField(name, offset, bitFieldOffset, 1)
+	f.Kind = BoolKind
+	return f
+}
+
+// boolOf returns the boolean stored in the bool field. It returns an error if
+// a strict field contains a value other than 0 and 1.
+func (f *Field) boolOf(data []byte) (bool, error) {
+	v := f.uintOf(data)
+	if f.Strict && v > 1 {
+		return false, fmt.Errorf("invalid boolean 0x%02x", v)
+	}
+	return v != 0, nil
+}
+
+//BoolE method returns the bool field indicated by fieldName. Unlike Get, it
+//returns an error if a strict field contains a value other than 0 and 1. It
+//returns an error wrapping ErrFieldAbsent for an absent optional field and an
+//error for a field that is not a bool.
+//
+//BoolE panics for a non-existing field name.
+func (s *Struct) BoolE(fieldName string) (bool, error) {
+	field := s.resolvePresent(fieldName)
+	switch {
+	case field == nil:
+		return false, fmt.Errorf("field %s: %w", fieldName, ErrFieldAbsent)
+	case field.Kind != BoolKind:
+		return false, fmt.Errorf("field %s of kind %s is not a bool", fieldName, field.Kind)
+	}
+	b, err := field.boolOf(s.Value)
+	if err != nil {
+		return false, fmt.Errorf("field %s: %w", fieldName, err)
+	}
+	return b, nil
+}
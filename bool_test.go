@@ -0,0 +1,70 @@
+package bmstruct
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bool fields", func() {
+	var tmpl *Template
+	var s *Struct
+
+	BeforeEach(func() {
+		strict := BoolField("strict", 1)
+		strict.Strict = true
+		tmpl = NewTemplate(-1,
+			BoolField("enabled", 0),
+			strict,
+			BoolBitField("flags.ext", 2, 3),
+			OptionalField(Uint16Field("ext", 3), "flags.ext"),
+		)
+		s = tmpl.Empty()
+	})
+	It("should read and write booleans", func() {
+		Expect(s.Get("enabled")).To(BeFalse())
+		Expect(s.Set("enabled", true)).To(Succeed())
+		Expect(s.Lookup("enabled")).To(Equal(Value{1}))
+		Expect(s.Lookup("enabled").Bool()).To(BeTrue())
+		s.Update("enabled", Value{7})
+		Expect(s.Get("enabled")).To(BeTrue())
+		s.Update("enabled", Bool(false))
+		Expect(s.Get("enabled")).To(BeFalse())
+		Expect(s.Set("enabled", 1)).NotTo(Succeed())
+	})
+	It("should store booleans in bits", func() {
+		Expect(s.Set("flags.ext", true)).To(Succeed())
+		Expect(s.Lookup("flags.ext")).To(Equal(Value{1}))
+		Expect(s.Value[2]).To(Equal(byte(0x08)))
+		Expect(s.Get("flags.ext")).To(BeTrue())
+		Expect(s.Present("ext")).To(BeTrue())
+		Expect(s.Set("flags.ext", false)).To(Succeed())
+		Expect(s.Value[2]).To(Equal(byte(0)))
+	})
+	It("should validate strict fields", func() {
+		s.Update("strict", Value{2})
+		_, err := s.BoolE("strict")
+		Expect(err).To(HaveOccurred())
+		Expect(s.Get("strict")).To(Equal(Value{2}))
+		s.Update("strict", Value{1})
+		b, err := s.BoolE("strict")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(b).To(BeTrue())
+		_, err = s.BoolE("ext")
+		Expect(err).To(MatchError(ContainSubstring("absent")))
+	})
+	It("should be available as columns", func() {
+		ss := NewTemplate(-1, BoolField("b", 0), BoolBitField("bit", 1, 0)).
+			Slice(Value{0, 1, 2, 0})
+		Expect(ss.Column("b")).To(Equal([]bool{false, true}))
+		Expect(ss.Column("bit")).To(Equal([]bool{true, false}))
+		ss.SetColumn("b", []bool{true, false})
+		Expect(ss.Value).To(Equal(Value{1, 1, 0, 0}))
+	})
+	It("should be used in expressions", func() {
+		t := NewTemplate(-1, BoolField("b", 0), BoolBitField("bit", 1, 0))
+		e, err := t.Compile("b == 1 && bit")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(e.Eval(t.New(Value{2, 1}))).To(Equal(int64(1)))
+		Expect(e.Eval(t.New(Value{0, 1}))).To(Equal(int64(0)))
+	})
+})
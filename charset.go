@@ -0,0 +1,324 @@
+package bmstruct
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+//Charset converts between Go strings and the encoded form of the strings stored
+//in string Fields. Charsets are referred to by name in the Charset option of
+//the Fields, see RegisterCharset.
+type Charset interface {
+	//Encode converts s to its encoded form. It returns an error if s contains
+	//a character that the Charset cannot represent.
+	Encode(s string) ([]byte, error)
+	//Decode converts the encoded b to a Go string. The length of b is a
+	//multiple of UnitLen.
+	Decode(b []byte) string
+	//UnitLen returns the size of the code units in bytes, e.g. 2 for UTF-16.
+	UnitLen() int
+	//RuneLen returns the length of the encoded character r in bytes or -1 if
+	//the Charset cannot represent r. The encoded form of a string is the
+	//concatenation of its encoded characters.
+	RuneLen(r rune) int
+}
+
+//TableCharset is a single byte Charset defined by the Unicode code points of
+//the 256 byte values, e.g. a code page.
+type TableCharset struct {
+	decode [256]rune
+	encode map[rune]byte
+}
+
+//NewTableCharset creates a new TableCharset from the table that maps each byte
+//value to a Unicode code point.
+//
+//NewTableCharset panics if a code point is used for more byte values.
+func NewTableCharset(table [256]rune) *TableCharset {
+	c := &TableCharset{
+		decode: table,
+		encode: make(map[rune]byte, len(table)),
+	}
+	for b, r := range table {
+		if _, found := c.encode[r]; found {
+			panic(fmt.Sprintf("code point %U is used for more byte values", r))
+		}
+		c.encode[r] = byte(b)
+	}
+	return c
+}
+
+//Encode implements the Charset interface.
+func (c *TableCharset) Encode(s string) ([]byte, error) {
+	b := make([]byte, 0, len(s))
+	for i, r := range s {
+		e, found := c.encode[r]
+		if !found {
+			return nil, fmt.Errorf("character %q at %d cannot be encoded", r, i)
+		}
+		b = append(b, e)
+	}
+	return b, nil
+}
+
+//Decode implements the Charset interface.
+func (c *TableCharset) Decode(b []byte) string {
+	var sb strings.Builder
+	sb.Grow(len(b))
+	for _, e := range b {
+		sb.WriteRune(c.decode[e])
+	}
+	return sb.String()
+}
+
+//UnitLen implements the Charset interface.
+func (c *TableCharset) UnitLen() int {
+	return 1
+}
+
+//RuneLen implements the Charset interface.
+func (c *TableCharset) RuneLen(r rune) int {
+	if _, found := c.encode[r]; !found {
+		return -1
+	}
+	return 1
+}
+
+type utf8Charset struct{}
+
+func (utf8Charset) Encode(s string) ([]byte, error) {
+	if !utf8.ValidString(s) {
+		return nil, fmt.Errorf("string is not valid UTF-8")
+	}
+	return []byte(s), nil
+}
+
+func (utf8Charset) Decode(b []byte) string {
+	return string(b)
+}
+
+func (utf8Charset) UnitLen() int {
+	return 1
+}
+
+func (utf8Charset) RuneLen(r rune) int {
+	return utf8.RuneLen(r)
+}
+
+type asciiCharset struct{}
+
+func (asciiCharset) Encode(s string) ([]byte, error) {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return nil, fmt.Errorf("non-ASCII character at %d", i)
+		}
+	}
+	return []byte(s), nil
+}
+
+func (asciiCharset) Decode(b []byte) string {
+	return string(b)
+}
+
+func (asciiCharset) UnitLen() int {
+	return 1
+}
+
+func (asciiCharset) RuneLen(r rune) int {
+	if r < 0 || r >= utf8.RuneSelf {
+		return -1
+	}
+	return 1
+}
+
+type utf16Charset struct {
+	bigEndian bool
+}
+
+func (c utf16Charset) Encode(s string) ([]byte, error) {
+	if !utf8.ValidString(s) {
+		return nil, fmt.Errorf("string is not valid UTF-8")
+	}
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(units))
+	for i, u := range units {
+		if c.bigEndian {
+			b[2*i], b[2*i+1] = byte(u>>8), byte(u)
+		} else {
+			b[2*i], b[2*i+1] = byte(u), byte(u>>8)
+		}
+	}
+	return b, nil
+}
+
+func (c utf16Charset) Decode(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		if c.bigEndian {
+			units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+		} else {
+			units[i] = uint16(b[2*i]) | uint16(b[2*i+1])<<8
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+func (c utf16Charset) UnitLen() int {
+	return 2
+}
+
+func (c utf16Charset) RuneLen(r rune) int {
+	if r >= 0x10000 {
+		return 4
+	}
+	return 2
+}
+
+// latin1Table maps the bytes of ISO-8859-1 to the same code points.
+var latin1Table = func() (table [256]rune) {
+	for b := range table {
+		table[b] = rune(b)
+	}
+	return table
+}()
+
+// ebcdic037Table is the IBM code page 037 (EBCDIC US/Canada).
+var ebcdic037Table = [256]rune{
+	0x00, 0x01, 0x02, 0x03, 0x9c, 0x09, 0x86, 0x7f,
+	0x97, 0x8d, 0x8e, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+	0x10, 0x11, 0x12, 0x13, 0x9d, 0x85, 0x08, 0x87,
+	0x18, 0x19, 0x92, 0x8f, 0x1c, 0x1d, 0x1e, 0x1f,
+	0x80, 0x81, 0x82, 0x83, 0x84, 0x0a, 0x17, 0x1b,
+	0x88, 0x89, 0x8a, 0x8b, 0x8c, 0x05, 0x06, 0x07,
+	0x90, 0x91, 0x16, 0x93, 0x94, 0x95, 0x96, 0x04,
+	0x98, 0x99, 0x9a, 0x9b, 0x14, 0x15, 0x9e, 0x1a,
+	0x20, 0xa0, 0xe2, 0xe4, 0xe0, 0xe1, 0xe3, 0xe5,
+	0xe7, 0xf1, 0xa2, 0x2e, 0x3c, 0x28, 0x2b, 0x7c,
+	0x26, 0xe9, 0xea, 0xeb, 0xe8, 0xed, 0xee, 0xef,
+	0xec, 0xdf, 0x21, 0x24, 0x2a, 0x29, 0x3b, 0xac,
+	0x2d, 0x2f, 0xc2, 0xc4, 0xc0, 0xc1, 0xc3, 0xc5,
+	0xc7, 0xd1, 0xa6, 0x2c, 0x25, 0x5f, 0x3e, 0x3f,
+	0xf8, 0xc9, 0xca, 0xcb, 0xc8, 0xcd, 0xce, 0xcf,
+	0xcc, 0x60, 0x3a, 0x23, 0x40, 0x27, 0x3d, 0x22,
+	0xd8, 0x61, 0x62, 0x63, 0x64, 0x65, 0x66, 0x67,
+	0x68, 0x69, 0xab, 0xbb, 0xf0, 0xfd, 0xfe, 0xb1,
+	0xb0, 0x6a, 0x6b, 0x6c, 0x6d, 0x6e, 0x6f, 0x70,
+	0x71, 0x72, 0xaa, 0xba, 0xe6, 0xb8, 0xc6, 0xa4,
+	0xb5, 0x7e, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78,
+	0x79, 0x7a, 0xa1, 0xbf, 0xd0, 0xdd, 0xde, 0xae,
+	0x5e, 0xa3, 0xa5, 0xb7, 0xa9, 0xa7, 0xb6, 0xbc,
+	0xbd, 0xbe, 0x5b, 0x5d, 0xaf, 0xa8, 0xb4, 0xd7,
+	0x7b, 0x41, 0x42, 0x43, 0x44, 0x45, 0x46, 0x47,
+	0x48, 0x49, 0xad, 0xf4, 0xf6, 0xf2, 0xf3, 0xf5,
+	0x7d, 0x4a, 0x4b, 0x4c, 0x4d, 0x4e, 0x4f, 0x50,
+	0x51, 0x52, 0xb9, 0xfb, 0xfc, 0xf9, 0xfa, 0xff,
+	0x5c, 0xf7, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58,
+	0x59, 0x5a, 0xb2, 0xd4, 0xd6, 0xd2, 0xd3, 0xd5,
+	0x30, 0x31, 0x32, 0x33, 0x34, 0x35, 0x36, 0x37,
+	0x38, 0x39, 0xb3, 0xdb, 0xdc, 0xd9, 0xda, 0x9f,
+}
+
+// ebcdic500Table is the IBM code page 500 (EBCDIC International).
+var ebcdic500Table = [256]rune{
+	0x00, 0x01, 0x02, 0x03, 0x9c, 0x09, 0x86, 0x7f,
+	0x97, 0x8d, 0x8e, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+	0x10, 0x11, 0x12, 0x13, 0x9d, 0x85, 0x08, 0x87,
+	0x18, 0x19, 0x92, 0x8f, 0x1c, 0x1d, 0x1e, 0x1f,
+	0x80, 0x81, 0x82, 0x83, 0x84, 0x0a, 0x17, 0x1b,
+	0x88, 0x89, 0x8a, 0x8b, 0x8c, 0x05, 0x06, 0x07,
+	0x90, 0x91, 0x16, 0x93, 0x94, 0x95, 0x96, 0x04,
+	0x98, 0x99, 0x9a, 0x9b, 0x14, 0x15, 0x9e, 0x1a,
+	0x20, 0xa0, 0xe2, 0xe4, 0xe0, 0xe1, 0xe3, 0xe5,
+	0xe7, 0xf1, 0x5b, 0x2e, 0x3c, 0x28, 0x2b, 0x21,
+	0x26, 0xe9, 0xea, 0xeb, 0xe8, 0xed, 0xee, 0xef,
+	0xec, 0xdf, 0x5d, 0x24, 0x2a, 0x29, 0x3b, 0x5e,
+	0x2d, 0x2f, 0xc2, 0xc4, 0xc0, 0xc1, 0xc3, 0xc5,
+	0xc7, 0xd1, 0xa6, 0x2c, 0x25, 0x5f, 0x3e, 0x3f,
+	0xf8, 0xc9, 0xca, 0xcb, 0xc8, 0xcd, 0xce, 0xcf,
+	0xcc, 0x60, 0x3a, 0x23, 0x40, 0x27, 0x3d, 0x22,
+	0xd8, 0x61, 0x62, 0x63, 0x64, 0x65, 0x66, 0x67,
+	0x68, 0x69, 0xab, 0xbb, 0xf0, 0xfd, 0xfe, 0xb1,
+	0xb0, 0x6a, 0x6b, 0x6c, 0x6d, 0x6e, 0x6f, 0x70,
+	0x71, 0x72, 0xaa, 0xba, 0xe6, 0xb8, 0xc6, 0xa4,
+	0xb5, 0x7e, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78,
+	0x79, 0x7a, 0xa1, 0xbf, 0xd0, 0xdd, 0xde, 0xae,
+	0xa2, 0xa3, 0xa5, 0xb7, 0xa9, 0xa7, 0xb6, 0xbc,
+	0xbd, 0xbe, 0xac, 0x7c, 0xaf, 0xa8, 0xb4, 0xd7,
+	0x7b, 0x41, 0x42, 0x43, 0x44, 0x45, 0x46, 0x47,
+	0x48, 0x49, 0xad, 0xf4, 0xf6, 0xf2, 0xf3, 0xf5,
+	0x7d, 0x4a, 0x4b, 0x4c, 0x4d, 0x4e, 0x4f, 0x50,
+	0x51, 0x52, 0xb9, 0xfb, 0xfc, 0xf9, 0xfa, 0xff,
+	0x5c, 0xf7, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58,
+	0x59, 0x5a, 0xb2, 0xd4, 0xd6, 0xd2, 0xd3, 0xd5,
+	0x30, 0x31, 0x32, 0x33, 0x34, 0x35, 0x36, 0x37,
+	0x38, 0x39, 0xb3, 0xdb, 0xdc, 0xd9, 0xda, 0x9f,
+}
+
+// registeredCharset is a Charset with its padding characters encoded in
+// advance, so string fields can be padded without encoding the padding byte on
+// every access.
+type registeredCharset struct {
+	Charset
+	padding [256][]byte
+}
+
+func newRegisteredCharset(c Charset) *registeredCharset {
+	r := &registeredCharset{Charset: c}
+	for i := range r.padding {
+		b, err := c.Encode(string(rune(i)))
+		if err != nil || len(b) == 0 {
+			b = []byte{byte(i)}
+		}
+		r.padding[i] = b
+	}
+	return r
+}
+
+var charsets = struct {
+	sync.RWMutex
+	byName map[string]*registeredCharset
+}{
+	byName: map[string]*registeredCharset{
+		"":           newRegisteredCharset(utf8Charset{}),
+		"utf-8":      newRegisteredCharset(utf8Charset{}),
+		"ascii":      newRegisteredCharset(asciiCharset{}),
+		"utf-16le":   newRegisteredCharset(utf16Charset{}),
+		"utf-16be":   newRegisteredCharset(utf16Charset{bigEndian: true}),
+		"latin1":     newRegisteredCharset(NewTableCharset(latin1Table)),
+		"iso-8859-1": newRegisteredCharset(NewTableCharset(latin1Table)),
+		"ebcdic-037": newRegisteredCharset(NewTableCharset(ebcdic037Table)),
+		"cp037":      newRegisteredCharset(NewTableCharset(ebcdic037Table)),
+		"ebcdic-500": newRegisteredCharset(NewTableCharset(ebcdic500Table)),
+		"cp500":      newRegisteredCharset(NewTableCharset(ebcdic500Table)),
+	},
+}
+
+//RegisterCharset function makes the Charset available by the given name for the
+//Charset option of string Fields. The built-in Charsets are "utf-8" (the
+//default), "ascii", "utf-16le", "utf-16be", "latin1" (or "iso-8859-1"),
+//"ebcdic-037" (or "cp037") and "ebcdic-500" (or "cp500").
+//
+//RegisterCharset panics if a Charset is already registered with the name.
+func RegisterCharset(name string, c Charset) {
+	registered := newRegisteredCharset(c)
+	charsets.Lock()
+	defer charsets.Unlock()
+	if _, found := charsets.byName[name]; found {
+		panic(fmt.Sprintf("charset %s is already registered", name))
+	}
+	charsets.byName[name] = registered
+}
+
+// lookupCharset returns the Charset registered by the name.
+func lookupCharset(name string) (*registeredCharset, error) {
+	charsets.RLock()
+	defer charsets.RUnlock()
+	c, found := charsets.byName[name]
+	if !found {
+		return nil, fmt.Errorf("unknown charset %s", name)
+	}
+	return c, nil
+}
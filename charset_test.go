@@ -0,0 +1,67 @@
+package bmstruct
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Charset", func() {
+	newField := func(charset string, padding byte) *Struct {
+		field := StringField("text", 0, 6, padding)
+		field.Charset = charset
+		return NewTemplate(-1, field).Empty()
+	}
+
+	It("should transcode EBCDIC", func() {
+		s := newField("ebcdic-037", ' ')
+		Expect(s.Set("text", "HELLO")).To(Succeed())
+		Expect(s.Value).To(Equal(Value{0xc8, 0xc5, 0xd3, 0xd3, 0xd6, 0x40}))
+		Expect(s.Get("text")).To(Equal("HELLO"))
+		Expect(s.Set("text", "[a1]")).To(Succeed())
+		Expect(s.Value).To(Equal(Value{0xba, 0x81, 0xf1, 0xbb, 0x40, 0x40}))
+		s = newField("cp500", 0)
+		Expect(s.Set("text", "[a1]")).To(Succeed())
+		Expect(s.Value).To(Equal(Value{0x4a, 0x81, 0xf1, 0x5a, 0, 0}))
+		Expect(s.Get("text")).To(Equal("[a1]"))
+	})
+	It("should transcode Latin-1", func() {
+		s := newField("latin1", 0)
+		Expect(s.Set("text", "café")).To(Succeed())
+		Expect(s.Value).To(Equal(Value{'c', 'a', 'f', 0xe9, 0, 0}))
+		Expect(s.Get("text")).To(Equal("café"))
+		Expect(s.Set("text", "€")).NotTo(Succeed())
+	})
+	It("should truncate at character boundaries", func() {
+		field := StringField("text", 0, 3, 0)
+		field.Charset = "iso-8859-1"
+		field.Truncate = true
+		s := NewTemplate(-1, field).Empty()
+		Expect(s.Set("text", "ééééé")).To(Succeed())
+		Expect(s.Value).To(Equal(Value{0xe9, 0xe9, 0xe9}))
+	})
+	It("should use registered charsets", func() {
+		var table [256]rune
+		for b := range table {
+			table[b] = rune(b ^ 0x80)
+		}
+		RegisterCharset("test-flipped", NewTableCharset(table))
+		s := newField("test-flipped", 0)
+		Expect(s.Set("text", "A")).To(Succeed())
+		Expect(s.Value[0]).To(Equal(byte(0xc1)))
+		Expect(s.Get("text")).To(Equal("A"))
+		Expect(func() {
+			RegisterCharset("test-flipped", NewTableCharset(table))
+		}).To(Panic())
+		table[1] = table[0]
+		Expect(func() {
+			NewTableCharset(table)
+		}).To(Panic())
+	})
+	It("should report unknown charsets", func() {
+		s := newField("no-such-charset", 0)
+		Expect(s.Set("text", "a")).NotTo(Succeed())
+		Expect(func() {
+			s.Get("text")
+		}).To(Panic())
+	})
+})
@@ -0,0 +1,117 @@
+package bmstruct
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// copyFields returns copies of the Fields of the Template in declaration order.
+func (t *Template) copyFields() []*Field {
+	fields := t.orderedFields()
+	copies := make([]*Field, len(fields))
+	for i, field := range fields {
+		c := *field
+		copies[i] = &c
+	}
+	return copies
+}
+
+func checkFieldNames(fields []*Field) error {
+	names := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if names[field.Name] {
+			return fmt.Errorf("duplicate field name %s", field.Name)
+		}
+		names[field.Name] = true
+	}
+	return nil
+}
+
+//Extend method creates a new Template that contains the Fields of the Template
+//followed by the given fields. The size parameter is interpreted as in
+//NewTemplate, i.e. a negative size is calculated from the Fields. The original
+//Template is not modified.
+//
+//Extend panics when a new field has the same name as an existing one or when
+//the given size is too small.
+func (t *Template) Extend(size int, fields ...*Field) *Template {
+	all := append(t.copyFields(), fields...)
+	if err := checkFieldNames(all); err != nil {
+		panic(err.Error())
+	}
+	return NewTemplate(size, all...)
+}
+
+//Embed method creates a new Template that contains the Fields of the Template
+//and the Fields of the other Template flattened at the given offset. The names
+//of the embedded Fields are prefixed with prefix and a dot, e.g. embedding a
+//field "type" with the prefix "hdr" results in "hdr.type". The references of
+//the embedded Fields to other Fields (the size fields of dynamic fields, the
+//presence fields of optional fields and the discriminators of variants) are
+//prefixed the same way. An empty prefix keeps the names unchanged. The size of
+//the new Template is extended to fit the other Template if necessary. The
+//original Templates are not modified.
+//
+//Embed panics when an embedded field has the same name as an existing one.
+func (t *Template) Embed(other *Template, offset uint64, prefix string) *Template {
+	all := t.copyFields()
+	for _, field := range other.copyFields() {
+		field.Offset += offset
+		if prefix != "" {
+			field.Name = prefixed(prefix, field.Name)
+			field.LenField = prefixed(prefix, field.LenField)
+			field.CountField = prefixed(prefix, field.CountField)
+			field.PresentIf = prefixed(prefix, field.PresentIf)
+			field.Discriminator = prefixed(prefix, field.Discriminator)
+		}
+		all = append(all, field)
+	}
+	if err := checkFieldNames(all); err != nil {
+		panic(err.Error())
+	}
+	size := t.Size
+	if end := int(offset) + other.Size; end > size {
+		size = end
+	}
+	return NewTemplate(size, all...)
+}
+
+// prefixed returns the name of a field prefixed with prefix and a dot. An
+// empty name, i.e. an unset reference to a field, remains empty.
+func prefixed(prefix, name string) string {
+	if name == "" {
+		return ""
+	}
+	return prefix + "." + name
+}
+
+//Merge function creates a new Template that contains the Fields of all the
+//given Templates. The size of the new Template is the largest size of the given
+//Templates. Fields with the same name shall be identical in every Template they
+//appear in, otherwise Merge returns an error. The given Templates are not
+//modified.
+func Merge(templates ...*Template) (*Template, error) {
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("at least 1 template shall be specified")
+	}
+	merged := []*Field{}
+	byName := map[string]*Field{}
+	size := 0
+	for _, t := range templates {
+		if t.Size > size {
+			size = t.Size
+		}
+		for _, field := range t.copyFields() {
+			existing, found := byName[field.Name]
+			if !found {
+				byName[field.Name] = field
+				merged = append(merged, field)
+				continue
+			}
+			if !reflect.DeepEqual(existing, field) {
+				return nil, fmt.Errorf("conflicting definitions of field %s", field.Name)
+			}
+		}
+	}
+	return NewTemplate(size, merged...), nil
+}
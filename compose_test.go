@@ -0,0 +1,118 @@
+package bmstruct
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Template composition", func() {
+	var header *Template
+
+	BeforeEach(func() {
+		header = NewTemplate(4,
+			Uint8Field("type", 0),
+			Uint8Field("flags", 1),
+			Uint16Field("len", 2),
+		)
+	})
+	Describe("Extend", func() {
+		It("should append the fields", func() {
+			t := header.Extend(-1, Uint32Field("seq", 4))
+			Expect(t.FieldNames()).To(Equal([]string{"type", "flags", "len", "seq"}))
+			Expect(t.Size).To(Equal(8))
+			Expect(header.FieldNames()).To(Equal([]string{"type", "flags", "len"}))
+			Expect(header.Size).To(Equal(4))
+		})
+		It("should copy the fields", func() {
+			t := header.Extend(16)
+			t.Fields["len"].Offset = 8
+			Expect(header.Fields["len"].Offset).To(Equal(uint64(2)))
+			Expect(t.Size).To(Equal(16))
+		})
+		It("should panic for duplicate names", func() {
+			Expect(func() {
+				header.Extend(-1, Uint32Field("len", 4))
+			}).To(Panic())
+		})
+		It("should panic for too small size", func() {
+			Expect(func() {
+				header.Extend(6, Uint32Field("seq", 4))
+			}).To(Panic())
+		})
+	})
+	Describe("Embed", func() {
+		It("should flatten the fields under the prefix", func() {
+			msg := NewTemplate(-1, Uint32Field("id", 0))
+			t := msg.Embed(header, 4, "hdr")
+			Expect(t.FieldNames()).To(Equal([]string{"id", "hdr.type", "hdr.flags", "hdr.len"}))
+			Expect(t.Fields["hdr.len"].Offset).To(Equal(uint64(6)))
+			Expect(t.Size).To(Equal(8))
+			Expect(msg.Size).To(Equal(4))
+			Expect(header.Fields["len"].Offset).To(Equal(uint64(2)))
+		})
+		It("should keep the names without prefix", func() {
+			t := NewTemplate(12, Uint32Field("id", 4)).Embed(header, 0, "")
+			Expect(t.FieldNames()).To(Equal([]string{"id", "type", "flags", "len"}))
+			Expect(t.Size).To(Equal(12))
+		})
+		It("should panic for duplicate names", func() {
+			Expect(func() {
+				header.Embed(header, 4, "")
+			}).To(Panic())
+		})
+		It("should prefix the size fields of dynamic fields", func() {
+			body := NewTemplate(-1,
+				Uint8Field("len", 0),
+				DynamicField("data", 1, "len"),
+			)
+			t := NewTemplate(-1, Uint8Field("len", 0)).Embed(body, 1, "hdr")
+			Expect(t.Fields["hdr.data"].LenField).To(Equal("hdr.len"))
+			s := t.Empty()
+			s.Update("len", Value{7})
+			Expect(s.Set("hdr.data", Value{1, 2})).To(Succeed())
+			Expect(s.Value).To(Equal(Value{7, 2, 1, 2}))
+			Expect(body.Fields["data"].LenField).To(Equal("len"))
+		})
+		It("should prefix the presence fields of optional fields", func() {
+			opt := NewTemplate(-1,
+				BitField("p", 0, 0, 1),
+				OptionalField(Uint8Field("o", 1), "p"),
+			)
+			t := NewTemplate(-1, Uint8Field("id", 0)).Embed(opt, 1, "hdr")
+			Expect(t.Fields["hdr.o"].PresentIf).To(Equal("hdr.p"))
+			s := t.New(Value{5, 1, 9})
+			Expect(s.Lookup("hdr.o")).To(Equal(Value{9}))
+		})
+		It("should prefix the discriminators of variants", func() {
+			v := NewTemplate(-1,
+				Uint8Field("type", 0),
+				VariantField("body", 1, "type",
+					&Case{Name: "a", Value: 1, Template: NewTemplate(-1, Uint8Field("x", 0))},
+					&Case{Name: "b", Value: 2, Template: NewTemplate(-1, Uint16Field("y", 0))},
+				),
+			)
+			t := header.Embed(v, 4, "msg")
+			Expect(t.Fields["msg.body"].Discriminator).To(Equal("msg.type"))
+			s := t.New(Value{0, 0, 0, 0, 2, 0x34, 0x12})
+			Expect(s.Variant("msg.body").Name).To(Equal("b"))
+			Expect(s.Lookup("msg.body.b.y").Uint16()).To(Equal(uint16(0x1234)))
+		})
+	})
+	Describe("Merge", func() {
+		It("should merge the fields", func() {
+			ping := header.Extend(-1, Uint32Field("seq", 4))
+			data := header.Extend(-1, Uint16Field("port", 4), Uint16Field("crc", 6))
+			t, err := Merge(ping, data)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(t.FieldNames()).To(Equal([]string{"type", "flags", "len", "seq", "port", "crc"}))
+			Expect(t.Size).To(Equal(8))
+		})
+		It("should detect conflicts", func() {
+			other := NewTemplate(4, Uint16Field("type", 0))
+			_, err := Merge(header, other)
+			Expect(err).To(HaveOccurred())
+			_, err = Merge()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
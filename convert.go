@@ -1,10 +1,5 @@
 package bmstruct
 
-import (
-	"fmt"
-	"reflect"
-)
-
 // bitmap                                         = 0b11001010 0b10101010
 // offset                                         = 4          0
 // len                                            = 2          1
@@ -52,69 +47,51 @@ func bitFieldOfByte(b byte, offset, length uint8) byte {
 	return (b >> uint(offset)) & ((1 << uint(length)) - 1)
 }
 
-// nthByteOfInt returns the nth byte of an int type that is represented as
-// []byte. A mutable slice is returned
-func nthByteOfInt(kindOfInt interface{}, n int) byte {
-	if n < 0 {
-		panic("n shall be larger than 0")
+// uintOfBytes returns the little-endian unsigned integer stored in b. The
+// length of b shall not exceed 8.
+func uintOfBytes(b []byte) uint64 {
+	if len(b) > 8 {
+		panic("byte slice is too long for an integer")
+	}
+	i := uint64(0)
+	for n := range b {
+		i |= uint64(b[n]) << uint(n*8)
+	}
+	return i
+}
+
+// putUintBytes stores the lowest len(b) bytes of v into b in little-endian
+// order. The length of b shall not exceed 8.
+func putUintBytes(b []byte, v uint64) {
+	if len(b) > 8 {
+		panic("byte slice is too long for an integer")
+	}
+	for n := range b {
+		b[n] = byte(v >> uint(n*8))
+	}
+}
+
+// uintOfBytesBE returns the big-endian unsigned integer stored in b. The length
+// of b shall not exceed 8.
+func uintOfBytesBE(b []byte) uint64 {
+	if len(b) > 8 {
+		panic("byte slice is too long for an integer")
+	}
+	i := uint64(0)
+	for _, c := range b {
+		i = i<<8 | uint64(c)
+	}
+	return i
+}
+
+// putUintBytesBE stores the lowest len(b) bytes of v into b in big-endian
+// order. The length of b shall not exceed 8.
+func putUintBytesBE(b []byte, v uint64) {
+	if len(b) > 8 {
+		panic("byte slice is too long for an integer")
 	}
-	switch iValue := kindOfInt.(type) {
-	case int:
-		if n > 7 {
-			panic("n is out of bounds")
-		}
-		return byte(iValue & (0xff << uint(n*8)) >> (uint(n * 8)))
-	case uint:
-		if n > 7 {
-			panic("n is out of bounds")
-		}
-		return byte(iValue & (0xff << uint(n*8)) >> (uint(n * 8)))
-	case int8:
-		if n > 0 {
-			panic("n is out of bounds")
-		}
-		return byte(iValue)
-	case uint8:
-		if n > 0 {
-			panic("n is out of bounds")
-		}
-		return iValue & (0xff << uint(n*8)) >> (uint(n * 8))
-	case int16:
-		if n > 1 {
-			panic("n is out of bounds")
-		}
-		return byte(iValue & (0xff << uint(n*8)) >> (uint(n * 8)))
-	case uint16:
-		if n > 1 {
-			panic("n is out of bounds")
-		}
-		return byte(iValue & (0xff << uint(n*8)) >> (uint(n * 8)))
-	case int32:
-		if n > 3 {
-			panic("n is out of bounds")
-		}
-		return byte(iValue & (0xff << uint(n*8)) >> (uint(n * 8)))
-	case uint32:
-		if n > 3 {
-			panic("n is out of bounds")
-		}
-		return byte(iValue & (0xff << uint(n*8)) >> (uint(n * 8)))
-	case int64:
-		if n > 7 {
-			panic("n is out of bounds")
-		}
-		return byte(iValue & (0xff << uint(n*8)) >> (uint(n * 8)))
-	case uint64:
-		if n > 7 {
-			panic("n is out of bounds")
-		}
-		return byte(iValue & (0xff << uint(n*8)) >> (uint(n * 8)))
-	case uintptr:
-		if n > 7 {
-			panic("n is out of bounds")
-		}
-		return byte(iValue & (0xff << uint(n*8)) >> (uint(n * 8)))
-	default:
-		panic(fmt.Sprintf("nthByteOfInt does not handle %s", reflect.TypeOf(kindOfInt).Kind()))
+	for n := len(b) - 1; n >= 0; n-- {
+		b[n] = byte(v)
+		v >>= 8
 	}
 }
@@ -188,436 +188,38 @@ var _ = Describe("Convert", func() {
 			})
 		})
 	})
-	Describe("nthByteOfInt", func() {
-		Context("for string", func() {
-			It("shall panic", func() {
-				Expect(func() {
-					nthByteOfInt("is a string", 0)
-				}).To(Panic())
-			})
-		})
-		Context("for int", func() {
-			var v int
-
-			BeforeEach(func() {
-				v = 0x0123456789ABCDEF
-			})
-			It("shall return the correct values for n = 0-7", func() {
-				b := make([]byte, 8)
-				for i := range b {
-					b[i] = nthByteOfInt(v, i)
-				}
-				Expect(b).To(Equal([]byte{
-					0xEF, 0xCD, 0xAB, 0x89,
-					0x67, 0x45, 0x23, 0x01,
-				}))
-			})
-			It("shall panic for n < 0", func() {
-				Expect(func() {
-					nthByteOfInt(v, -42)
-				}).To(Panic())
-			})
-			It("shall panic for n > 7", func() {
-				Expect(func() {
-					nthByteOfInt(v, 8)
-				}).To(Panic())
-			})
-		})
-		Context("for negative int", func() {
-			var v int
-
-			BeforeEach(func() {
-				v = -0x0123456789ABCDEF
-			})
-			It("shall return the correct values for n = 0-7", func() {
-				b := make([]byte, 8)
-				for i := range b {
-					b[i] = nthByteOfInt(v, i)
-				}
-				Expect(b).To(Equal([]byte{
-					0x11, 0x32, 0x54, 0x76,
-					0x98, 0xBA, 0xDC, 0xFE,
-				}))
-			})
-			It("shall panic for n < 0", func() {
-				Expect(func() {
-					nthByteOfInt(v, -42)
-				}).To(Panic())
-			})
-			It("shall panic for n > 7", func() {
-				Expect(func() {
-					nthByteOfInt(v, 8)
-				}).To(Panic())
-			})
-		})
-		Context("for uint", func() {
-			var v uint
-
-			BeforeEach(func() {
-				v = 0x0123456789ABCDEF
-			})
-			It("shall return the correct values for n = 0-7", func() {
-				b := make([]byte, 8)
-				for i := range b {
-					b[i] = nthByteOfInt(v, i)
-				}
-				Expect(b).To(Equal([]byte{
-					0xEF, 0xCD, 0xAB, 0x89,
-					0x67, 0x45, 0x23, 0x01,
-				}))
-			})
-			It("shall panic for n < 0", func() {
-				Expect(func() {
-					nthByteOfInt(v, -42)
-				}).To(Panic())
-			})
-			It("shall panic for n > 7", func() {
-				Expect(func() {
-					nthByteOfInt(v, 8)
-				}).To(Panic())
-			})
-		})
-		Context("for int8", func() {
-			var v int8
-
-			BeforeEach(func() {
-				v = 0x01
-			})
-			It("shall return the correct values for n = 0", func() {
-				b := make([]byte, 1)
-				for i := range b {
-					b[i] = nthByteOfInt(v, i)
-				}
-				Expect(b).To(Equal([]byte{
-					0x01,
-				}))
-			})
-			It("shall panic for n < 0", func() {
-				Expect(func() {
-					nthByteOfInt(v, -42)
-				}).To(Panic())
-			})
-			It("shall panic for n > 0", func() {
-				Expect(func() {
-					nthByteOfInt(v, 1)
-				}).To(Panic())
-			})
+	Describe("uintOfBytes", func() {
+		It("shall return the little-endian integer", func() {
+			Expect(uintOfBytes([]byte{})).To(Equal(uint64(0)))
+			Expect(uintOfBytes([]byte{0xEF})).To(Equal(uint64(0xEF)))
+			Expect(uintOfBytes([]byte{0xEF, 0xCD, 0xAB})).To(Equal(uint64(0xABCDEF)))
+			Expect(uintOfBytes([]byte{
+				0xEF, 0xCD, 0xAB, 0x89,
+				0x67, 0x45, 0x23, 0x01,
+			})).To(Equal(uint64(0x0123456789ABCDEF)))
+		})
+		It("shall panic for more than 8 bytes", func() {
+			Expect(func() {
+				uintOfBytes(make([]byte, 9))
+			}).To(Panic())
 		})
-		Context("for negative int8", func() {
-			var v int8
-
-			BeforeEach(func() {
-				v = -0x01
-			})
-			It("shall return the correct values for n = 0", func() {
-				b := make([]byte, 1)
-				for i := range b {
-					b[i] = nthByteOfInt(v, i)
-				}
-				Expect(b).To(Equal([]byte{
-					0xFF,
-				}))
-			})
-			It("shall panic for n < 0", func() {
-				Expect(func() {
-					nthByteOfInt(v, -42)
-				}).To(Panic())
-			})
-			It("shall panic for n > 0", func() {
-				Expect(func() {
-					nthByteOfInt(v, 1)
-				}).To(Panic())
-			})
-		})
-		Context("for uint8", func() {
-			var v uint8
-
-			BeforeEach(func() {
-				v = 0x01
-			})
-			It("shall return the correct values for n = 0", func() {
-				b := make([]byte, 1)
-				for i := range b {
-					b[i] = nthByteOfInt(v, i)
-				}
-				Expect(b).To(Equal([]byte{
-					0x01,
-				}))
-			})
-			It("shall panic for n < 0", func() {
-				Expect(func() {
-					nthByteOfInt(v, -42)
-				}).To(Panic())
-			})
-			It("shall panic for n > 0", func() {
-				Expect(func() {
-					nthByteOfInt(v, 1)
-				}).To(Panic())
-			})
-		})
-		Context("for int16", func() {
-			var v int16
-
-			BeforeEach(func() {
-				v = 0x0123
-			})
-			It("shall return the correct values for n = 0-1", func() {
-				b := make([]byte, 2)
-				for i := range b {
-					b[i] = nthByteOfInt(v, i)
-				}
-				Expect(b).To(Equal([]byte{
-					0x23, 0x01,
-				}))
-			})
-			It("shall panic for n < 0", func() {
-				Expect(func() {
-					nthByteOfInt(v, -42)
-				}).To(Panic())
-			})
-			It("shall panic for n > 1", func() {
-				Expect(func() {
-					nthByteOfInt(v, 2)
-				}).To(Panic())
-			})
-		})
-		Context("for negative int16", func() {
-			var v int16
-
-			BeforeEach(func() {
-				v = -0x0123
-			})
-			It("shall return the correct values for n = 0-1", func() {
-				b := make([]byte, 2)
-				for i := range b {
-					b[i] = nthByteOfInt(v, i)
-				}
-				Expect(b).To(Equal([]byte{
-					0xDD, 0xFE,
-				}))
-			})
-			It("shall panic for n < 0", func() {
-				Expect(func() {
-					nthByteOfInt(v, -42)
-				}).To(Panic())
-			})
-			It("shall panic for n > 1", func() {
-				Expect(func() {
-					nthByteOfInt(v, 2)
-				}).To(Panic())
-			})
-		})
-		Context("for uint16", func() {
-			var v uint16
-
-			BeforeEach(func() {
-				v = 0x0123
-			})
-			It("shall return the correct values for n = 0-1", func() {
-				b := make([]byte, 2)
-				for i := range b {
-					b[i] = nthByteOfInt(v, i)
-				}
-				Expect(b).To(Equal([]byte{
-					0x23, 0x01,
-				}))
-			})
-			It("shall panic for n < 0", func() {
-				Expect(func() {
-					nthByteOfInt(v, -42)
-				}).To(Panic())
-			})
-			It("shall panic for n > 1", func() {
-				Expect(func() {
-					nthByteOfInt(v, 2)
-				}).To(Panic())
-			})
-		})
-		Context("for int32", func() {
-			var v int32
-
-			BeforeEach(func() {
-				v = 0x01234567
-			})
-			It("shall return the correct values for n = 0-3", func() {
-				b := make([]byte, 4)
-				for i := range b {
-					b[i] = nthByteOfInt(v, i)
-				}
-				Expect(b).To(Equal([]byte{
-					0x67, 0x45, 0x23, 0x01,
-				}))
-			})
-			It("shall panic for n < 0", func() {
-				Expect(func() {
-					nthByteOfInt(v, -42)
-				}).To(Panic())
-			})
-			It("shall panic for n > 3", func() {
-				Expect(func() {
-					nthByteOfInt(v, 4)
-				}).To(Panic())
-			})
-		})
-		Context("for negative int32", func() {
-			var v int32
-
-			BeforeEach(func() {
-				v = -0x01234567
-			})
-			It("shall return the correct values for n = 0-3", func() {
-				b := make([]byte, 4)
-				for i := range b {
-					b[i] = nthByteOfInt(v, i)
-				}
-				Expect(b).To(Equal([]byte{
-					0x99, 0xBA, 0xDC, 0xFE,
-				}))
-			})
-			It("shall panic for n < 0", func() {
-				Expect(func() {
-					nthByteOfInt(v, -42)
-				}).To(Panic())
-			})
-			It("shall panic for n > 3", func() {
-				Expect(func() {
-					nthByteOfInt(v, 4)
-				}).To(Panic())
-			})
-		})
-		Context("for uint32", func() {
-			var v uint32
-
-			BeforeEach(func() {
-				v = 0x01234567
-			})
-			It("shall return the correct values for n = 0-3", func() {
-				b := make([]byte, 4)
-				for i := range b {
-					b[i] = nthByteOfInt(v, i)
-				}
-				Expect(b).To(Equal([]byte{
-					0x67, 0x45, 0x23, 0x01,
-				}))
-			})
-			It("shall panic for n < 0", func() {
-				Expect(func() {
-					nthByteOfInt(v, -42)
-				}).To(Panic())
-			})
-			It("shall panic for n > 3", func() {
-				Expect(func() {
-					nthByteOfInt(v, 4)
-				}).To(Panic())
-			})
-		})
-		Context("for int64", func() {
-			var v int64
-
-			BeforeEach(func() {
-				v = 0x0123456789ABCDEF
-			})
-			It("shall return the correct values for n = 0-7", func() {
-				b := make([]byte, 8)
-				for i := range b {
-					b[i] = nthByteOfInt(v, i)
-				}
-				Expect(b).To(Equal([]byte{
-					0xEF, 0xCD, 0xAB, 0x89,
-					0x67, 0x45, 0x23, 0x01,
-				}))
-			})
-			It("shall panic for n < 0", func() {
-				Expect(func() {
-					nthByteOfInt(v, -42)
-				}).To(Panic())
-			})
-			It("shall panic for n > 7", func() {
-				Expect(func() {
-					nthByteOfInt(v, 8)
-				}).To(Panic())
-			})
-		})
-		Context("for negative int64", func() {
-			var v int64
-
-			BeforeEach(func() {
-				v = -0x0123456789ABCDEF
-			})
-			It("shall return the correct values for n = 0-7", func() {
-				b := make([]byte, 8)
-				for i := range b {
-					b[i] = nthByteOfInt(v, i)
-				}
-				Expect(b).To(Equal([]byte{
-					0x11, 0x32, 0x54, 0x76,
-					0x98, 0xBA, 0xDC, 0xFE,
-				}))
-			})
-			It("shall panic for n < 0", func() {
-				Expect(func() {
-					nthByteOfInt(v, -42)
-				}).To(Panic())
-			})
-			It("shall panic for n > 7", func() {
-				Expect(func() {
-					nthByteOfInt(v, 8)
-				}).To(Panic())
-			})
-		})
-		Context("for uint64", func() {
-			var v uint64
-
-			BeforeEach(func() {
-				v = 0x0123456789ABCDEF
-			})
-			It("shall return the correct values for n = 0-7", func() {
-				b := make([]byte, 8)
-				for i := range b {
-					b[i] = nthByteOfInt(v, i)
-				}
-				Expect(b).To(Equal([]byte{
-					0xEF, 0xCD, 0xAB, 0x89,
-					0x67, 0x45, 0x23, 0x01,
-				}))
-			})
-			It("shall panic for n < 0", func() {
-				Expect(func() {
-					nthByteOfInt(v, -42)
-				}).To(Panic())
-			})
-			It("shall panic for n > 7", func() {
-				Expect(func() {
-					nthByteOfInt(v, 8)
-				}).To(Panic())
-			})
-		})
-		Context("for uintptr", func() {
-			var v uintptr
-
-			BeforeEach(func() {
-				v = 0x0123456789ABCDEF
-			})
-			It("shall return the correct values for n = 0-7", func() {
-				b := make([]byte, 8)
-				for i := range b {
-					b[i] = nthByteOfInt(v, i)
-				}
-				Expect(b).To(Equal([]byte{
-					0xEF, 0xCD, 0xAB, 0x89,
-					0x67, 0x45, 0x23, 0x01,
-				}))
-			})
-			It("shall panic for n < 0", func() {
-				Expect(func() {
-					nthByteOfInt(v, -42)
-				}).To(Panic())
-			})
-			It("shall panic for n > 7", func() {
-				Expect(func() {
-					nthByteOfInt(v, 8)
-				}).To(Panic())
-			})
+	})
+	Describe("putUintBytes", func() {
+		It("shall store the lowest bytes in little-endian order", func() {
+			b := make([]byte, 3)
+			putUintBytes(b, 0x0123456789ABCDEF)
+			Expect(b).To(Equal([]byte{0xEF, 0xCD, 0xAB}))
+			b = make([]byte, 8)
+			putUintBytes(b, 0x0123456789ABCDEF)
+			Expect(b).To(Equal([]byte{
+				0xEF, 0xCD, 0xAB, 0x89,
+				0x67, 0x45, 0x23, 0x01,
+			}))
+		})
+		It("shall panic for more than 8 bytes", func() {
+			Expect(func() {
+				putUintBytes(make([]byte, 9), 1)
+			}).To(Panic())
 		})
 	})
 })
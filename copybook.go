@@ -0,0 +1,598 @@
+package bmstruct
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+//ParseCopybook function reads a COBOL copybook from r and returns the Template
+//of the record it describes. The characters of the record are encoded in the
+//given charset, e.g. "cp037" for EBCDIC or "" for ASCII, see RegisterCharset.
+//
+//The copybook may be in fixed format (sequence numbers in columns 1-6, the
+//indicator in column 7 and the code in columns 8-72) or in free format. The
+//data description entries are mapped to Fields as follows:
+//
+//  PIC X(n), A(n) and edited pictures   StringField padded with spaces
+//  PIC S9(n)V9(m) [DISPLAY]             ZonedDecimalField
+//  PIC S9(n)V9(m) COMP-3                PackedDecimalField
+//  COMP, COMP-4, COMP-5, BINARY         BytesKind Field of 2, 4 or 8 bytes
+//  COMP-1, COMP-2                       BytesKind Field of 4 or 8 bytes
+//  group items                          BytesKind Field spanning the group
+//
+//The name of a Field is the path of the data names from the level below the
+//01 level, joined by dots, e.g. "CUSTOMER.NAME". FILLER items have no Field
+//and no part in the path, but their space is reserved. A REDEFINES item gets
+//the offset of the item it redefines. An item with OCCURS n TIMES is repeated
+//n times; the occurrences are named by their 1-based index, e.g. "ITEMS.2.QTY",
+//and the whole table is an array Field, e.g. "ITEMS", whose Elem Template
+//describes a single occurrence, so the occurrences can be reached with
+//Struct.Array too. A table with OCCURS DEPENDING ON is laid out for its maximum
+//number of occurrences, since its object is a decimal rather than an integer
+//count field (see ArrayField). Several 01 levels describe alternative layouts
+//of the same record starting at offset 0.
+//
+//Level 88 entries and VALUE clauses are ignored. ParseCopybook returns an error
+//for unsupported features like level 66 (RENAMES), SIGN SEPARATE, SYNCHRONIZED,
+//pictures with scaling positions (P) or more than 18 digits and for tables with
+//so many occurrences that the copybook would describe more than 262144 Fields.
+func ParseCopybook(r io.Reader, charset string) (*Template, error) {
+	if _, err := lookupCharset(charset); err != nil {
+		return nil, err
+	}
+	text, err := readCopybook(r)
+	if err != nil {
+		return nil, err
+	}
+	records, err := parseCopybookItems(copybookEntries(text))
+	if err != nil {
+		return nil, err
+	}
+	l := &copybookLayout{charset: charset}
+	size := uint64(0)
+	for _, record := range records {
+		path := ""
+		if len(record.children) == 0 {
+			path = record.name
+		}
+		n, err := l.add(record, 0, path, "")
+		if err != nil {
+			return nil, err
+		}
+		if n > size {
+			size = n
+		}
+	}
+	if len(l.fields) == 0 {
+		return nil, fmt.Errorf("copybook describes no fields")
+	}
+	if size > uint64(maxInt) {
+		return nil, fmt.Errorf("record of %d bytes is too large", size)
+	}
+	if err := checkFieldNames(l.fields); err != nil {
+		return nil, err
+	}
+	return NewTemplate(int(size), l.fields...), nil
+}
+
+// readCopybook returns the code of the copybook without comments and sequence
+// numbers.
+func readCopybook(r io.Reader) (string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	fixed := true
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" && (len(line) < 7 ||
+			strings.Trim(line[:6], "0123456789 ") != "" ||
+			!strings.ContainsRune(" *-/Dd", rune(line[6]))) {
+			fixed = false
+			break
+		}
+	}
+	var b strings.Builder
+	for _, line := range lines {
+		if fixed && len(line) >= 7 {
+			if line[6] != ' ' && line[6] != '-' {
+				continue
+			}
+			line = line[7:]
+			if len(line) > 65 {
+				line = line[:65]
+			}
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "*") {
+			continue
+		}
+		if i := strings.Index(line, "*>"); i >= 0 {
+			line = line[:i]
+		}
+		b.WriteString(line)
+		b.WriteByte(' ')
+	}
+	return b.String(), nil
+}
+
+// copybookEntries splits the code of a copybook into the tokens of its entries.
+// An entry is terminated by a period followed by a space. Literals are returned
+// as single tokens, commas and semicolons followed by a space are separators.
+func copybookEntries(text string) [][]string {
+	var entries [][]string
+	var tokens []string
+	isSpace := func(i int) bool {
+		return i >= len(text) || text[i] == ' ' || text[i] == '\t'
+	}
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || ((c == ',' || c == ';') && isSpace(i+1)):
+			i++
+		case c == '.' && isSpace(i+1):
+			if len(tokens) > 0 {
+				entries = append(entries, tokens)
+				tokens = nil
+			}
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(text) && text[j] != c {
+				j++
+			}
+			if j < len(text) {
+				j++
+			}
+			tokens = append(tokens, text[i:j])
+			i = j
+		default:
+			j := i
+			for j < len(text) && !isSpace(j) && !(text[j] == '.' && isSpace(j+1)) &&
+				!((text[j] == ',' || text[j] == ';') && isSpace(j+1)) {
+				j++
+			}
+			tokens = append(tokens, text[i:j])
+			i = j
+		}
+	}
+	if len(tokens) > 0 {
+		entries = append(entries, tokens)
+	}
+	return entries
+}
+
+// copybookItem is a data description entry of a copybook.
+type copybookItem struct {
+	level     int
+	name      string
+	picture   string
+	usage     string
+	redefines string
+	occurs    int
+	children  []*copybookItem
+}
+
+// copybookClauses are the keywords that start a clause of an entry.
+var copybookClauses = map[string]bool{
+	"PIC": true, "PICTURE": true, "USAGE": true, "REDEFINES": true,
+	"OCCURS": true, "VALUE": true, "VALUES": true, "SIGN": true,
+	"LEADING": true, "TRAILING": true, "SYNC": true, "SYNCHRONIZED": true,
+	"JUST": true, "JUSTIFIED": true, "BLANK": true, "GLOBAL": true,
+	"EXTERNAL": true, "RENAMES": true,
+}
+
+// copybookUsages maps the usages to their canonical names.
+var copybookUsages = map[string]string{
+	"DISPLAY":         "DISPLAY",
+	"COMP":            "COMP",
+	"COMPUTATIONAL":   "COMP",
+	"COMP-4":          "COMP",
+	"COMPUTATIONAL-4": "COMP",
+	"COMP-5":          "COMP",
+	"COMPUTATIONAL-5": "COMP",
+	"BINARY":          "COMP",
+	"COMP-1":          "COMP-1",
+	"COMPUTATIONAL-1": "COMP-1",
+	"COMP-2":          "COMP-2",
+	"COMPUTATIONAL-2": "COMP-2",
+	"COMP-3":          "COMP-3",
+	"COMPUTATIONAL-3": "COMP-3",
+	"PACKED-DECIMAL":  "COMP-3",
+}
+
+// keyword returns whether the token starts a clause.
+func keyword(token string) bool {
+	token = strings.ToUpper(token)
+	return copybookClauses[token] || copybookUsages[token] != ""
+}
+
+// parseCopybookItems parses the entries and returns the tree of items under
+// the 01 and 77 levels.
+func parseCopybookItems(entries [][]string) ([]*copybookItem, error) {
+	var records, stack []*copybookItem
+	for _, tokens := range entries {
+		item, err := parseCopybookItem(tokens)
+		if err != nil {
+			return nil, err
+		}
+		if item == nil {
+			continue
+		}
+		for len(stack) > 0 && stack[len(stack)-1].level >= item.level {
+			stack = stack[:len(stack)-1]
+		}
+		switch {
+		case item.level == 1 || item.level == 77:
+			records = append(records, item)
+			stack = []*copybookItem{item}
+			continue
+		case len(stack) == 0:
+			return nil, fmt.Errorf("level %02d item %s outside of a record",
+				item.level, item.name)
+		}
+		parent := stack[len(stack)-1]
+		if parent.picture != "" || parent.level == 77 {
+			return nil, fmt.Errorf("elementary item %s cannot contain item %s",
+				parent.name, item.name)
+		}
+		if item.usage == "" {
+			item.usage = parent.usage
+		}
+		parent.children = append(parent.children, item)
+		stack = append(stack, item)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("copybook describes no record")
+	}
+	return records, nil
+}
+
+// parseCopybookItem parses the tokens of a data description entry. It returns
+// nil for level 88 entries.
+func parseCopybookItem(tokens []string) (*copybookItem, error) {
+	level, err := strconv.Atoi(tokens[0])
+	if err != nil || level < 1 || (level > 49 && level != 66 && level != 77 && level != 88) {
+		return nil, fmt.Errorf("invalid level number %s", tokens[0])
+	}
+	switch level {
+	case 88:
+		return nil, nil
+	case 66:
+		return nil, fmt.Errorf("level 66 (RENAMES) is not supported")
+	}
+	item := &copybookItem{level: level}
+	i := 1
+	if i < len(tokens) && !keyword(tokens[i]) {
+		if !strings.EqualFold(tokens[i], "FILLER") {
+			item.name = tokens[i]
+		}
+		i++
+	}
+	next := func() string {
+		if i < len(tokens) {
+			i++
+			return tokens[i-1]
+		}
+		return ""
+	}
+	optional := func(words ...string) {
+		for _, word := range words {
+			if i < len(tokens) && strings.EqualFold(tokens[i], word) {
+				i++
+			}
+		}
+	}
+	skip := func() {
+		for i < len(tokens) && !keyword(tokens[i]) {
+			i++
+		}
+	}
+	for i < len(tokens) {
+		token := strings.ToUpper(next())
+		switch token {
+		case "PIC", "PICTURE":
+			optional("IS")
+			if item.picture = next(); item.picture == "" {
+				return nil, fmt.Errorf("item %s: missing picture string", item.name)
+			}
+		case "USAGE":
+			optional("IS")
+			usage := strings.ToUpper(next())
+			if item.usage = copybookUsages[usage]; item.usage == "" {
+				return nil, fmt.Errorf("item %s: usage %s is not supported", item.name, usage)
+			}
+		case "REDEFINES":
+			if item.redefines = next(); item.redefines == "" {
+				return nil, fmt.Errorf("item %s: missing redefined item", item.name)
+			}
+		case "OCCURS":
+			n, err := strconv.Atoi(next())
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("item %s: invalid OCCURS clause", item.name)
+			}
+			if i < len(tokens) && strings.EqualFold(tokens[i], "TO") {
+				i++
+				if n, err = strconv.Atoi(next()); err != nil || n < 0 {
+					return nil, fmt.Errorf("item %s: invalid OCCURS clause", item.name)
+				}
+			}
+			item.occurs = n
+			optional("TIMES")
+			skip()
+		case "SIGN", "LEADING", "TRAILING":
+			if token == "SIGN" {
+				optional("IS")
+				token = strings.ToUpper(next())
+			}
+			if token == "LEADING" || (i < len(tokens) && strings.EqualFold(tokens[i], "SEPARATE")) {
+				return nil, fmt.Errorf("item %s: only SIGN TRAILING is supported", item.name)
+			}
+		case "SYNC", "SYNCHRONIZED":
+			return nil, fmt.Errorf("item %s: SYNCHRONIZED is not supported", item.name)
+		case "RENAMES":
+			return nil, fmt.Errorf("item %s: RENAMES is not supported", item.name)
+		case "VALUE", "VALUES", "JUST", "JUSTIFIED", "BLANK", "GLOBAL", "EXTERNAL":
+			skip()
+		default:
+			if item.usage = copybookUsages[token]; item.usage == "" {
+				return nil, fmt.Errorf("item %s: unexpected %s", item.name, token)
+			}
+		}
+	}
+	return item, nil
+}
+
+// copybookPicture is the parsed form of a picture string.
+type copybookPicture struct {
+	numeric bool
+	signed  bool
+	digits  int
+	scale   int
+	length  int
+}
+
+// parsePicture parses a picture string like S9(5)V99 or X(10).
+func parsePicture(picture string) (*copybookPicture, error) {
+	p := &copybookPicture{numeric: true}
+	point := false
+	s := strings.ToUpper(picture)
+	for i := 0; i < len(s); i++ {
+		c, n := s[i], 1
+		if i+1 < len(s) && s[i+1] == '(' {
+			end := strings.IndexByte(s[i:], ')')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid picture %s", picture)
+			}
+			count, err := strconv.Atoi(s[i+2 : i+end])
+			if err != nil || count <= 0 {
+				return nil, fmt.Errorf("invalid picture %s", picture)
+			}
+			n = count
+			i += end
+		}
+		switch c {
+		case '9':
+			p.digits += n
+			p.length += n
+			if point {
+				p.scale += n
+			}
+		case 'S':
+			if p.length > 0 || p.signed || n != 1 {
+				return nil, fmt.Errorf("invalid picture %s", picture)
+			}
+			p.signed = true
+		case 'V':
+			if point || n != 1 {
+				return nil, fmt.Errorf("invalid picture %s", picture)
+			}
+			point = true
+		case 'P':
+			return nil, fmt.Errorf("scaling position in picture %s is not supported", picture)
+		case 'X', 'A', 'Z', '*', '+', '-', '.', ',', 'B', '0', '/', '$', 'C', 'R', 'D':
+			p.numeric = false
+			p.length += n
+		default:
+			return nil, fmt.Errorf("invalid picture %s", picture)
+		}
+	}
+	switch {
+	case p.length == 0:
+		return nil, fmt.Errorf("invalid picture %s", picture)
+	case !p.numeric && (p.signed || point):
+		return nil, fmt.Errorf("invalid picture %s", picture)
+	case p.numeric && p.digits > maxDecimalDigits:
+		return nil, fmt.Errorf("picture %s has more than %d digits", picture, maxDecimalDigits)
+	}
+	return p, nil
+}
+
+// copybookLayout collects the Fields of the items of a copybook.
+type copybookLayout struct {
+	charset string
+	fields  []*Field
+}
+
+// join returns the path of the named item under prefix.
+func join(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// maxCopybookFields is the maximum number of Fields of a copybook. It limits
+// the Fields created for the occurrences of large tables.
+const maxCopybookFields = 1 << 18
+
+// add adds the Fields of the item at offset and returns the size of the item
+// with all of its occurrences. The name of the Field of the item is path, an
+// empty path means no Field. The Fields of the children are named under
+// prefix.
+func (l *copybookLayout) add(item *copybookItem, offset uint64, path, prefix string) (uint64, error) {
+	if item.occurs == 0 {
+		return l.addOccurrence(item, offset, path, prefix)
+	}
+	var table *Field
+	if path != "" {
+		table = &Field{Name: path, Offset: offset}
+		l.fields = append(l.fields, table)
+	}
+	size := uint64(0)
+	for i := 1; i <= item.occurs; i++ {
+		if len(l.fields) > maxCopybookFields {
+			return 0, fmt.Errorf("item %s: copybook describes more than %d fields",
+				item.name, maxCopybookFields)
+		}
+		name, childPrefix := "", prefix
+		if path != "" {
+			name = join(path, strconv.Itoa(i))
+			childPrefix = name
+		}
+		count := len(l.fields)
+		n, err := l.addOccurrence(item, offset+size, name, childPrefix)
+		if err != nil {
+			return 0, err
+		}
+		if n != 0 && uint64(item.occurs) > uint64(maxInt)/n {
+			return 0, fmt.Errorf("item %s: table of %d occurrences is too large",
+				item.name, item.occurs)
+		}
+		if len(l.fields) == count {
+			// The occurrences have no Fields, e.g. FILLER.
+			size = n * uint64(item.occurs)
+			break
+		}
+		size += n
+	}
+	if table != nil {
+		table.Len = size
+		elem, err := l.elem(item)
+		if err != nil {
+			return 0, err
+		}
+		table.Elem = elem
+	}
+	return size, nil
+}
+
+// elem returns the Template of a single occurrence of the table item or nil if
+// the occurrence has no Fields. The Fields of a group are named by their path
+// under the item, an elementary item has a single Field named by the item.
+func (l *copybookLayout) elem(item *copybookItem) (*Template, error) {
+	occurrence := &copybookLayout{charset: l.charset}
+	path := ""
+	if len(item.children) == 0 {
+		path = item.name
+	}
+	n, err := occurrence.addOccurrence(item, 0, path, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(occurrence.fields) == 0 {
+		return nil, nil
+	}
+	if err := checkFieldNames(occurrence.fields); err != nil {
+		return nil, err
+	}
+	return NewTemplate(int(n), occurrence.fields...), nil
+}
+
+// addOccurrence adds the Fields of a single occurrence of the item at offset.
+func (l *copybookLayout) addOccurrence(item *copybookItem, offset uint64,
+	path, prefix string) (uint64, error) {
+	if len(item.children) == 0 {
+		field, err := l.elementary(item, offset, path)
+		if err != nil {
+			return 0, err
+		}
+		if path != "" {
+			l.fields = append(l.fields, field)
+		}
+		return field.Len, nil
+	}
+	var group *Field
+	if path != "" {
+		group = &Field{Name: path, Offset: offset}
+		l.fields = append(l.fields, group)
+	}
+	offsets := make(map[string]uint64)
+	next, end := offset, offset
+	for _, child := range item.children {
+		childOffset := next
+		if child.redefines != "" {
+			redefined, found := offsets[strings.ToUpper(child.redefines)]
+			if !found {
+				return 0, fmt.Errorf("item %s redefines unknown item %s",
+					child.name, child.redefines)
+			}
+			childOffset = redefined
+		}
+		childPath, childPrefix := "", prefix
+		if child.name != "" {
+			offsets[strings.ToUpper(child.name)] = childOffset
+			childPath = join(prefix, child.name)
+			childPrefix = childPath
+		}
+		n, err := l.add(child, childOffset, childPath, childPrefix)
+		if err != nil {
+			return 0, err
+		}
+		if childOffset+n > next {
+			next = childOffset + n
+		}
+		if next > end {
+			end = next
+		}
+	}
+	if group != nil {
+		group.Len = end - offset
+	}
+	return end - offset, nil
+}
+// elementary returns the Field of an elementary item.
+func (l *copybookLayout) elementary(item *copybookItem, offset uint64, path string) (*Field, error) {
+	switch item.usage {
+	case "COMP-1":
+		return &Field{Name: path, Offset: offset, Len: 4}, nil
+	case "COMP-2":
+		return &Field{Name: path, Offset: offset, Len: 8}, nil
+	}
+	if item.picture == "" {
+		return nil, fmt.Errorf("elementary item %s has no picture", item.name)
+	}
+	p, err := parsePicture(item.picture)
+	if err != nil {
+		return nil, fmt.Errorf("item %s: %w", item.name, err)
+	}
+	if !p.numeric {
+		if item.usage != "" && item.usage != "DISPLAY" {
+			return nil, fmt.Errorf("item %s: usage %s of alphanumeric item",
+				item.name, item.usage)
+		}
+		field := StringField(path, offset, uint64(p.length), ' ')
+		field.Charset = l.charset
+		return field, nil
+	}
+	switch item.usage {
+	case "COMP-3":
+		return PackedDecimalField(path, offset, uint8(p.digits), uint8(p.scale), p.signed), nil
+	case "COMP":
+		field := &Field{Name: path, Offset: offset, Len: 8}
+		if p.digits <= 4 {
+			field.Len = 2
+		} else if p.digits <= 9 {
+			field.Len = 4
+		}
+		return field, nil
+	}
+	field := ZonedDecimalField(path, offset, uint8(p.digits), uint8(p.scale), p.signed)
+	field.Charset = l.charset
+	return field, nil
+}
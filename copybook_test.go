@@ -0,0 +1,141 @@
+package bmstruct
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const customerCopybook = `
+000100* CUSTOMER RECORD
+000200 01  CUSTOMER-RECORD.
+000300     05  CUST-ID             PIC 9(6).
+000400     05  CUST-NAME.
+000500         10  FIRST-NAME      PIC X(10).
+000600         10  LAST-NAME       PIC X(15).
+000700     05  BALANCE             PIC S9(7)V99 COMP-3.
+000800     05  BIRTH-DATE          PIC 9(8).
+000900     05  BIRTH-PARTS REDEFINES BIRTH-DATE.
+001000         10  BIRTH-YEAR      PIC 9(4).
+001100         10  FILLER          PIC X(4).
+001200     05  ORDER-COUNT         PIC 9(2) VALUE ZERO.
+001300     05  ORDERS OCCURS 3 TIMES.
+001400         10  ORDER-NO        PIC X(4).
+001500         10  AMOUNT          PIC S9(3)V9 USAGE IS COMP-3.
+001600     05  STATUS-CODE         PIC X.
+001700         88  ACTIVE          VALUE 'A'.
+001800     05  FILLER              PIC X(5).
+`
+
+var _ = Describe("Copybook", func() {
+	It("should lay out the record", func() {
+		tmpl, err := ParseCopybook(strings.NewReader(customerCopybook), "cp037")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tmpl.Size).To(Equal(6 + 25 + 5 + 8 + 2 + 3*7 + 1 + 5))
+		Expect(tmpl.FieldNames()).To(ConsistOf(
+			"CUST-ID", "CUST-NAME", "CUST-NAME.FIRST-NAME", "CUST-NAME.LAST-NAME",
+			"BALANCE", "BIRTH-DATE", "BIRTH-PARTS", "BIRTH-PARTS.BIRTH-YEAR",
+			"ORDER-COUNT", "ORDERS",
+			"ORDERS.1", "ORDERS.1.ORDER-NO", "ORDERS.1.AMOUNT",
+			"ORDERS.2", "ORDERS.2.ORDER-NO", "ORDERS.2.AMOUNT",
+			"ORDERS.3", "ORDERS.3.ORDER-NO", "ORDERS.3.AMOUNT",
+			"STATUS-CODE",
+		))
+		f := tmpl.Fields
+		Expect(f["CUST-ID"].Kind).To(Equal(ZonedDecimalKind))
+		Expect(f["CUST-NAME"].Kind).To(Equal(BytesKind))
+		Expect(f["CUST-NAME"].Len).To(Equal(uint64(25)))
+		Expect(f["CUST-NAME.LAST-NAME"].Offset).To(Equal(uint64(16)))
+		Expect(f["BALANCE"].Kind).To(Equal(PackedDecimalKind))
+		Expect(f["BALANCE"].Len).To(Equal(uint64(5)))
+		Expect(f["BALANCE"].Scale).To(Equal(uint8(2)))
+		Expect(f["BALANCE"].Signed).To(BeTrue())
+		Expect(f["BIRTH-PARTS"].Offset).To(Equal(f["BIRTH-DATE"].Offset))
+		Expect(f["BIRTH-PARTS.BIRTH-YEAR"].Offset).To(Equal(uint64(36)))
+		Expect(f["ORDER-COUNT"].Offset).To(Equal(uint64(44)))
+		Expect(f["ORDERS"].Len).To(Equal(uint64(21)))
+		Expect(f["ORDERS.2.AMOUNT"].Offset).To(Equal(uint64(46 + 7 + 4)))
+		Expect(f["STATUS-CODE"].Offset).To(Equal(uint64(67)))
+		Expect(f["STATUS-CODE"].Charset).To(Equal("cp037"))
+	})
+	It("should read and write the fields", func() {
+		tmpl, err := ParseCopybook(strings.NewReader(customerCopybook), "cp037")
+		Expect(err).NotTo(HaveOccurred())
+		s := tmpl.Empty()
+		Expect(s.Set("CUST-ID", 4711)).To(Succeed())
+		Expect(s.Lookup("CUST-ID")).To(Equal(Value{0xf0, 0xf0, 0xf4, 0xf7, 0xf1, 0xf1}))
+		Expect(s.Set("CUST-NAME.FIRST-NAME", "JOHN")).To(Succeed())
+		Expect(s.Lookup("CUST-NAME.FIRST-NAME")[4]).To(Equal(byte(0x40)))
+		Expect(s.Get("CUST-NAME.FIRST-NAME")).To(Equal("JOHN"))
+		Expect(s.Set("BALANCE", "-1234.50")).To(Succeed())
+		Expect(s.Lookup("BALANCE")).To(Equal(Value{0x00, 0x01, 0x23, 0x45, 0x0d}))
+		Expect(s.Set("BIRTH-DATE", 19700101)).To(Succeed())
+		Expect(s.Get("BIRTH-PARTS.BIRTH-YEAR")).To(Equal(Decimal{Unscaled: 1970}))
+		Expect(s.Set("ORDERS.3.AMOUNT", "12.5")).To(Succeed())
+		Expect(s.Get("ORDERS.3.AMOUNT").(Decimal).String()).To(Equal("12.5"))
+	})
+	It("should describe the occurrences of tables with element templates", func() {
+		tmpl, err := ParseCopybook(strings.NewReader(customerCopybook), "cp037")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tmpl.Fields["ORDERS"].Elem.FieldNames()).To(Equal([]string{"ORDER-NO", "AMOUNT"}))
+		s := tmpl.Empty()
+		Expect(s.Set("ORDERS.2.ORDER-NO", "A12")).To(Succeed())
+		orders := s.Array("ORDERS")
+		Expect(orders.Count()).To(Equal(uint32(3)))
+		Expect(orders.Nth(1).Get("ORDER-NO")).To(Equal("A12"))
+
+		tmpl, err = ParseCopybook(strings.NewReader(`
+01 REC.
+   05 CODES PIC X(2) OCCURS 4.
+   05 FILLER PIC X(10) OCCURS 99999999.
+`), "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tmpl.Fields["CODES"].Elem.FieldNames()).To(Equal([]string{"CODES"}))
+		Expect(tmpl.Fields["CODES"].Elem.Size).To(Equal(2))
+		Expect(tmpl.Size).To(Equal(8 + 999999990))
+	})
+	It("should limit the number of occurrences", func() {
+		_, err := ParseCopybook(strings.NewReader("01 R. 05 A PIC X OCCURS 999999999."), "")
+		Expect(err).To(MatchError(ContainSubstring("more than 262144 fields")))
+	})
+	It("should accept free format", func() {
+		tmpl, err := ParseCopybook(strings.NewReader(`
+01 REC.
+   05 KIND PIC X. *> record kind
+   05 ITEMS OCCURS 1 TO 2 TIMES DEPENDING ON N-ITEMS PIC 9(3) COMP-3.
+   05 RAW COMP-2.
+   05 N PIC S9(4) COMP.
+01 OTHER-REC.
+   05 PAYLOAD PIC X(20).
+`), "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tmpl.Size).To(Equal(20))
+		Expect(tmpl.Fields["ITEMS.2"].Offset).To(Equal(uint64(3)))
+		Expect(tmpl.Fields["RAW"].Len).To(Equal(uint64(8)))
+		Expect(tmpl.Fields["N"].Len).To(Equal(uint64(2)))
+		Expect(tmpl.Fields["PAYLOAD"].Offset).To(Equal(uint64(0)))
+	})
+	It("should report unsupported copybooks", func() {
+		for _, src := range []string{
+			"",
+			"05 A PIC X.",
+			"01 R. 05 A PIC X SIGN LEADING SEPARATE.",
+			"01 R. 05 A PIC X SYNC.",
+			"01 R. 05 A PIC 9(19).",
+			"01 R. 05 A PIC 99PP.",
+			"01 R. 05 A PIC X. 66 B RENAMES A.",
+			"01 R. 05 A PIC X. 05 B REDEFINES C PIC X.",
+			"01 R. 05 A PIC X. 10 B PIC X.",
+			"01 R. 05 A PIC X(.",
+			"01 R. 05 A.",
+			"01 R. 05 A PIC X. 05 A PIC X.",
+			"01 R. 05 A PIC X FOO.",
+		} {
+			_, err := ParseCopybook(strings.NewReader(src), "")
+			Expect(err).To(HaveOccurred(), src)
+		}
+		_, err := ParseCopybook(strings.NewReader("01 R PIC X."), "unknown")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,315 @@
+package bmstruct
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//Decimal is a fixed-point decimal number, its value is Unscaled * 10^-Scale,
+//e.g. Decimal{Unscaled: -12345, Scale: 2} is -123.45.
+type Decimal struct {
+	Unscaled int64
+	Scale    uint8
+}
+
+// maxDecimalDigits is the number of decimal digits that always fit in an int64.
+const maxDecimalDigits = 18
+
+//ParseDecimal function parses a decimal number like "-123.45". The Scale of the
+//result is the number of the fractional digits.
+func ParseDecimal(s string) (Decimal, error) {
+	digits := strings.TrimLeft(s, "+-")
+	if len(s)-len(digits) > 1 {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	scale := 0
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		scale = len(digits) - i - 1
+		digits = digits[:i] + digits[i+1:]
+	}
+	if digits == "" || strings.TrimLeft(digits, "0123456789") != "" ||
+		len(strings.TrimLeft(digits, "0")) > maxDecimalDigits || scale > maxDecimalDigits {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	unscaled, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	if strings.HasPrefix(s, "-") {
+		unscaled = -unscaled
+	}
+	return Decimal{Unscaled: unscaled, Scale: uint8(scale)}, nil
+}
+
+//String method returns the Decimal with Scale fractional digits, e.g.
+//"-123.45".
+func (d Decimal) String() string {
+	abs := d.Unscaled
+	sign := ""
+	if abs < 0 {
+		abs, sign = -abs, "-"
+	}
+	digits := strconv.FormatUint(uint64(abs), 10)
+	if d.Scale == 0 {
+		return sign + digits
+	}
+	if len(digits) <= int(d.Scale) {
+		digits = strings.Repeat("0", int(d.Scale)-len(digits)+1) + digits
+	}
+	i := len(digits) - int(d.Scale)
+	return sign + digits[:i] + "." + digits[i:]
+}
+
+//Float64 method returns the Decimal as float64. The conversion may lose
+//precision.
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// rescale returns the unscaled value of the Decimal with the given scale. It
+// returns an error if fractional digits would be lost or the result overflows.
+func (d Decimal) rescale(scale uint8) (int64, error) {
+	u := d.Unscaled
+	for s := d.Scale; s > scale; s-- {
+		if u%10 != 0 {
+			return 0, fmt.Errorf("%s cannot be represented with scale %d", d, scale)
+		}
+		u /= 10
+	}
+	for s := d.Scale; s < scale; s++ {
+		if u > maxInt64/10 || u < -maxInt64/10 {
+			return 0, fmt.Errorf("%s with scale %d: %w", d, scale, ErrOverflow)
+		}
+		u *= 10
+	}
+	return u, nil
+}
+
+const maxInt64 = 1<<63 - 1
+
+// decimalDigits returns the absolute value of u as a string of n digits. It
+// returns an error if u has more than n digits.
+func decimalDigits(u int64, n int) (string, error) {
+	if u < 0 {
+		u = -u
+	}
+	digits := strconv.FormatUint(uint64(u), 10)
+	if len(digits) > n {
+		return "", fmt.Errorf("%d has more than %d digits: %w", u, n, ErrOverflow)
+	}
+	return strings.Repeat("0", n-len(digits)) + digits, nil
+}
+
+//ZonedDecimalField function creates a new Field of ZonedDecimalKind with the
+//given name and offset that stores a number of the given digits, one digit per
+//byte, with scale fractional digits (COBOL PIC S9(n)V9(m) DISPLAY). The Len of
+//the Field is digits.
+//
+//The digits are encoded in the Charset of the Field, so the zone of the digits
+//is 0xF for EBCDIC charsets (e.g. "cp037") and 0x3 for ASCII compatible ones.
+//The sign of a signed number is stored in the zone of the last digit: 0xC
+//(positive) or 0xD (negative) in EBCDIC, 0x3 or 0x7 in ASCII. Unsigned numbers
+//use the zone of the Charset. On decoding, the overpunched characters '{', 'A'
+//to 'I' (positive) and '}', 'J' to 'R' (negative) are accepted too.
+//
+//The Get method of Struct returns a Decimal, or the Value of the field if it
+//does not contain a valid number. ZonedDecimalField panics if digits is 0 or
+//larger than 18 or scale is larger than digits.
+func ZonedDecimalField(name string, offset uint64, digits, scale uint8, signed bool) *Field {
+	checkDigits(digits, scale)
+	return &Field{
+		Name:   name,
+		Offset: offset,
+		Len:    uint64(digits),
+		Kind:   ZonedDecimalKind,
+		Digits: digits,
+		Scale:  scale,
+		Signed: signed,
+	}
+}
+
+//PackedDecimalField function creates a new Field of PackedDecimalKind with the
+//given name and offset that stores a number of the given digits, two digits per
+//byte followed by a sign nibble, with scale fractional digits (COBOL COMP-3).
+//The Len of the Field is digits/2+1.
+//
+//The sign nibble is 0xC (positive) or 0xD (negative) for signed and 0xF for
+//unsigned numbers. On decoding, 0xA, 0xC, 0xE and 0xF are accepted as positive,
+//0xB and 0xD as negative signs.
+//
+//The Get method of Struct returns a Decimal, or the Value of the field if it
+//does not contain a valid number. PackedDecimalField panics if digits is 0 or
+//larger than 18 or scale is larger than digits.
+func PackedDecimalField(name string, offset uint64, digits, scale uint8, signed bool) *Field {
+	checkDigits(digits, scale)
+	return &Field{
+		Name:   name,
+		Offset: offset,
+		Len:    uint64(digits)/2 + 1,
+		Kind:   PackedDecimalKind,
+		Digits: digits,
+		Scale:  scale,
+		Signed: signed,
+	}
+}
+
+func checkDigits(digits, scale uint8) {
+	if digits == 0 || digits > maxDecimalDigits || scale > digits {
+		panic(fmt.Sprintf("invalid decimal of %d digits with scale %d", digits, scale))
+	}
+}
+
+// decimalOf returns the number stored in the decimal field. It returns an
+// error for an invalid digit or sign.
+func (f *Field) decimalOf(data []byte) (Decimal, error) {
+	b := f.slice(data)
+	var digits []byte
+	negative := false
+	switch f.Kind {
+	case PackedDecimalKind:
+		digits = make([]byte, 0, 2*len(b))
+		for _, c := range b {
+			digits = append(digits, c>>4, c&0xf)
+		}
+		switch digits[len(digits)-1] {
+		case 0xa, 0xc, 0xe, 0xf:
+		case 0xb, 0xd:
+			negative = true
+		default:
+			return Decimal{}, fmt.Errorf("invalid sign nibble 0x%x", digits[len(digits)-1])
+		}
+		digits = digits[:len(digits)-1]
+		// An even number of digits is preceded by a pad nibble, which shall be
+		// zero.
+		for len(digits) > int(f.Digits) && f.Digits != 0 {
+			if digits[0] != 0 {
+				return Decimal{}, fmt.Errorf("invalid pad nibble 0x%x", digits[0])
+			}
+			digits = digits[1:]
+		}
+	case ZonedDecimalKind:
+		c, err := lookupCharset(f.Charset)
+		if err != nil {
+			return Decimal{}, err
+		}
+		zero := zeroOf(c)
+		digits = make([]byte, len(b))
+		for i, d := range b {
+			last := i == len(b)-1
+			switch {
+			case d&0xf0 == zero&0xf0 && d&0xf <= 9:
+				digits[i] = d & 0xf
+			case last && (d>>4 == 0xc || d>>4 == 0xd) && d&0xf <= 9 && zero == 0xf0:
+				digits[i], negative = d&0xf, d>>4 == 0xd
+			case last && d>>4 == 0x7 && d&0xf <= 9 && zero == 0x30:
+				digits[i], negative = d&0xf, true
+			case last && zero == 0x30 && strings.IndexByte("{ABCDEFGHI", d) >= 0:
+				digits[i] = byte(strings.IndexByte("{ABCDEFGHI", d))
+			case last && zero == 0x30 && strings.IndexByte("}JKLMNOPQR", d) >= 0:
+				digits[i], negative = byte(strings.IndexByte("}JKLMNOPQR", d)), true
+			default:
+				return Decimal{}, fmt.Errorf("invalid zoned digit 0x%02x at %d", d, i)
+			}
+		}
+	}
+	unscaled := int64(0)
+	for _, d := range digits {
+		if d > 9 {
+			return Decimal{}, fmt.Errorf("invalid digit 0x%x", d)
+		}
+		unscaled = unscaled*10 + int64(d)
+	}
+	if negative {
+		unscaled = -unscaled
+	}
+	return Decimal{Unscaled: unscaled, Scale: f.Scale}, nil
+}
+
+// zeroOf returns the digit 0 encoded in the single byte charset c.
+func zeroOf(c Charset) byte {
+	if b, err := c.Encode("0"); err == nil && len(b) == 1 {
+		return b[0]
+	}
+	return '0'
+}
+
+// encodeDecimal returns the Value of the decimal field. Besides Decimal, v may
+// be a string parsed by ParseDecimal or a Go integer.
+func (f *Field) encodeDecimal(v interface{}) (Value, error) {
+	var d Decimal
+	switch x := v.(type) {
+	case Decimal:
+		d = x
+	case string:
+		var err error
+		if d, err = ParseDecimal(x); err != nil {
+			return nil, err
+		}
+	default:
+		abs, negative, ok := integerOf(v)
+		if !ok || abs > maxInt64 {
+			return nil, fmt.Errorf("value of type %T cannot be stored in a field of kind %s",
+				v, f.Kind)
+		}
+		d.Unscaled = int64(abs)
+		if negative {
+			d.Unscaled = -d.Unscaled
+		}
+	}
+	unscaled, err := d.rescale(f.Scale)
+	if err != nil {
+		return nil, err
+	}
+	if unscaled < 0 && !f.Signed {
+		return nil, fmt.Errorf("negative %s: %w", d, ErrOverflow)
+	}
+	digits, err := decimalDigits(unscaled, int(f.Digits))
+	if err != nil {
+		return nil, err
+	}
+	value := make(Value, f.Len)
+	switch f.Kind {
+	case PackedDecimalKind:
+		sign := byte(0xf)
+		if f.Signed {
+			sign = 0xc
+			if unscaled < 0 {
+				sign = 0xd
+			}
+		}
+		nibbles := make([]byte, 0, 2*len(value))
+		if len(digits)%2 == 0 {
+			nibbles = append(nibbles, 0)
+		}
+		for i := 0; i < len(digits); i++ {
+			nibbles = append(nibbles, digits[i]-'0')
+		}
+		nibbles = append(nibbles, sign)
+		for i := range value {
+			value[i] = nibbles[2*i]<<4 | nibbles[2*i+1]
+		}
+	case ZonedDecimalKind:
+		c, err := lookupCharset(f.Charset)
+		if err != nil {
+			return nil, err
+		}
+		zero := zeroOf(c)
+		for i := 0; i < len(digits); i++ {
+			value[i] = zero&0xf0 | (digits[i] - '0')
+		}
+		if f.Signed {
+			last := &value[len(value)-1]
+			switch {
+			case zero == 0xf0 && unscaled < 0:
+				*last = 0xd0 | *last&0xf
+			case zero == 0xf0:
+				*last = 0xc0 | *last&0xf
+			case unscaled < 0:
+				*last = 0x70 | *last&0xf
+			}
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,118 @@
+package bmstruct
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Decimal", func() {
+	It("should be parsed and formatted", func() {
+		d, err := ParseDecimal("-123.45")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d).To(Equal(Decimal{Unscaled: -12345, Scale: 2}))
+		Expect(d.String()).To(Equal("-123.45"))
+		Expect(d.Float64()).To(Equal(-123.45))
+		Expect(Decimal{Unscaled: 5, Scale: 3}.String()).To(Equal("0.005"))
+		Expect(Decimal{Unscaled: 42}.String()).To(Equal("42"))
+		for _, s := range []string{"", "-", "1.2.3", "+-1", "12a", "1234567890123456789"} {
+			_, err := ParseDecimal(s)
+			Expect(err).To(HaveOccurred(), s)
+		}
+	})
+	Describe("fields", func() {
+		var tmpl *Template
+		var s *Struct
+
+		BeforeEach(func() {
+			ebcdic := ZonedDecimalField("ebcdic", 10, 3, 0, true)
+			ebcdic.Charset = "cp037"
+			tmpl = NewTemplate(-1,
+				PackedDecimalField("packed", 0, 5, 2, true),
+				ZonedDecimalField("zoned", 3, 4, 1, true),
+				ZonedDecimalField("count", 7, 3, 0, false),
+				ebcdic,
+				PackedDecimalField("even", 13, 4, 0, false),
+			)
+			s = tmpl.Empty()
+		})
+		It("should pack the digits with a sign nibble", func() {
+			Expect(tmpl.Fields["packed"].Len).To(Equal(uint64(3)))
+			Expect(s.Set("packed", Decimal{Unscaled: -12345, Scale: 2})).To(Succeed())
+			Expect(s.Lookup("packed")).To(Equal(Value{0x12, 0x34, 0x5d}))
+			Expect(s.Get("packed")).To(Equal(Decimal{Unscaled: -12345, Scale: 2}))
+			Expect(s.Set("packed", "1.5")).To(Succeed())
+			Expect(s.Lookup("packed")).To(Equal(Value{0x00, 0x15, 0x0c}))
+			Expect(s.Set("even", 1234)).To(Succeed())
+			Expect(s.Lookup("even")).To(Equal(Value{0x01, 0x23, 0x4f}))
+			s.Update("packed", Value{0x00, 0x01, 0x2b})
+			Expect(s.Get("packed").(Decimal).String()).To(Equal("-0.12"))
+		})
+		It("should zone the digits", func() {
+			Expect(s.Set("zoned", "-12.3")).To(Succeed())
+			Expect(s.Lookup("zoned")).To(Equal(Value("012s")))
+			Expect(s.Get("zoned")).To(Equal(Decimal{Unscaled: -123, Scale: 1}))
+			Expect(s.Set("count", uint8(7))).To(Succeed())
+			Expect(s.Lookup("count")).To(Equal(Value("007")))
+			Expect(s.Set("ebcdic", -42)).To(Succeed())
+			Expect(s.Lookup("ebcdic")).To(Equal(Value{0xf0, 0xf4, 0xd2}))
+			Expect(s.Get("ebcdic")).To(Equal(Decimal{Unscaled: -42}))
+			s.Update("ebcdic", Value{0xf1, 0xf2, 0xf3})
+			Expect(s.Get("ebcdic")).To(Equal(Decimal{Unscaled: 123}))
+		})
+		It("should decode overpunched signs", func() {
+			s.Update("zoned", Value("123}"))
+			Expect(s.Get("zoned").(Decimal).String()).To(Equal("-123.0"))
+			s.Update("zoned", Value("012C"))
+			Expect(s.Get("zoned").(Decimal).String()).To(Equal("12.3"))
+		})
+		It("should report invalid numbers", func() {
+			s.Update("zoned", Value("    "))
+			_, err := s.Decimal("zoned")
+			Expect(err).To(HaveOccurred())
+			Expect(s.Get("zoned")).To(Equal(Value("    ")))
+			s.Update("packed", Value{0x12, 0x34, 0x51})
+			_, err = s.Decimal("packed")
+			Expect(err).To(HaveOccurred())
+			_, err = tmpl.Empty().Decimal("packed")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should check the pad nibble of an even number of digits", func() {
+			t := NewTemplate(-1,
+				PackedDecimalField("two", 0, 2, 0, true),
+				PackedDecimalField("max", 2, 18, 0, true),
+			)
+			s := t.Empty()
+			s.Update("two", Value{0x91, 0x2c})
+			_, err := s.Decimal("two")
+			Expect(err).To(HaveOccurred())
+			s.Update("two", Value{0x01, 0x2c})
+			Expect(s.Decimal("two")).To(Equal(Decimal{Unscaled: 12}))
+			s.Update("max", Value{0x99, 0x99, 0x99, 0x99, 0x99, 0x99, 0x99, 0x99, 0x99, 0x9c})
+			_, err = s.Decimal("max")
+			Expect(err).To(HaveOccurred())
+			Expect(s.Set("max", "-999999999999999999")).To(Succeed())
+			Expect(s.Lookup("max")).To(Equal(Value{0x09, 0x99, 0x99, 0x99, 0x99, 0x99, 0x99, 0x99, 0x99, 0x9d}))
+			Expect(s.Decimal("max")).To(Equal(Decimal{Unscaled: -999999999999999999}))
+		})
+		It("should check the range and the scale", func() {
+			Expect(errors.Is(s.Set("packed", 1000), ErrOverflow)).To(BeTrue())
+			Expect(errors.Is(s.Set("count", -1), ErrOverflow)).To(BeTrue())
+			Expect(s.Set("packed", "1.234")).NotTo(Succeed())
+			Expect(s.Set("packed", "1.230")).To(Succeed())
+			Expect(s.Get("packed")).To(Equal(Decimal{Unscaled: 123, Scale: 2}))
+			Expect(s.Set("packed", 1.5)).NotTo(Succeed())
+		})
+		It("should compare numerically", func() {
+			a, b := tmpl.Empty(), tmpl.Empty()
+			Expect(a.Set("packed", -5)).To(Succeed())
+			Expect(b.Set("packed", 1)).To(Succeed())
+			Expect(tmpl.Fields["packed"].compare(a.Value, b.Value)).To(Equal(-1))
+		})
+		It("should panic for invalid digits", func() {
+			Expect(func() { PackedDecimalField("p", 0, 19, 0, true) }).To(Panic())
+			Expect(func() { ZonedDecimalField("z", 0, 2, 3, true) }).To(Panic())
+		})
+	})
+})
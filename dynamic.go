@@ -0,0 +1,340 @@
+package bmstruct
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+//ErrRecordTooLarge is returned (wrapped) by Parse and Decoder.Decode when the
+//size fields of a record describe a record larger than the largest int.
+var ErrRecordTooLarge = errors.New("record too large")
+
+// maxInt is the largest value of int.
+const maxInt = int(^uint(0) >> 1)
+
+//DynamicField function creates a new Field with the given name and offset
+//whose length in bytes is stored in the integer field indicated by lenField.
+//
+//A Template with dynamic fields describes variable-length records. The Size of
+//such a Template is the size of the record when every dynamic field is empty.
+//The Fields declared after a dynamic field at the same or a larger offset are
+//moved by the actual length of the dynamic field, e.g.
+//
+//  t := NewTemplate(-1,
+//      Uint16Field("len", 0),
+//      DynamicField("body", 2, "len"),
+//      Uint32Field("crc", 2),
+//  )
+//
+//describes a record with a 2 bytes long length, a body of that length and a 4
+//bytes long checksum right after the body. Use Template.Parse to map data to
+//such a Template and Struct.Update to change the length of a dynamic field,
+//either by updating the field or by updating its length field.
+func DynamicField(name string, offset uint64, lenField string) *Field {
+	return &Field{
+		Name:     name,
+		Offset:   offset,
+		LenField: lenField,
+	}
+}
+
+//ArrayField function creates a new Field with the given name and offset that
+//contains as many elements of the elem Template as the integer field indicated
+//by countField tells. See DynamicField for the layout of the Fields following
+//the array. The elements can be reached with the Struct.Array method.
+//
+//ArrayField panics if elem contains dynamic fields.
+func ArrayField(name string, offset uint64, countField string, elem *Template) *Field {
+	if elem.dynamic() {
+		panic(fmt.Sprintf("element template of array %s shall have a fixed size", name))
+	}
+	return &Field{
+		Name:       name,
+		Offset:     offset,
+		CountField: countField,
+		Elem:       elem,
+	}
+}
+
+// dynamic returns true if the length of the field depends on another field.
+// Optional fields are dynamic too, their length is 0 when they are absent.
+func (f *Field) dynamic() bool {
+	return f.LenField != "" || f.CountField != "" || f.PresentIf != ""
+}
+
+// sizeField returns the name of the field that the length of the dynamic field
+// depends on, i.e. its length, element count or presence field.
+func (f *Field) sizeField() string {
+	switch {
+	case f.CountField != "":
+		return f.CountField
+	case f.LenField != "":
+		return f.LenField
+	}
+	return f.PresentIf
+}
+
+// unitLen returns the number of bytes that a unit of the size field of the
+// dynamic field stands for.
+func (f *Field) unitLen() uint64 {
+	if f.Elem != nil {
+		return uint64(f.Elem.Size)
+	}
+	return 1
+}
+
+// dynamic returns true if the Template has dynamic fields.
+func (t *Template) dynamic() bool {
+	return t.fieldOrder().dynamic
+}
+
+// checkDynamic returns an error if the size field of a dynamic field does not
+// precede the dynamic field or it is not an integer of fixed length.
+func (t *Template) checkDynamic() error {
+	declared := make(map[string]bool)
+	for _, field := range t.orderedFields() {
+		declared[field.Name] = true
+		if !field.dynamic() {
+			continue
+		}
+		name := field.sizeField()
+		sizeField, found := t.Fields[name]
+		if !found || !declared[name] || !sizeField.isInteger() || sizeField.dynamic() {
+			return fmt.Errorf("size field %s of dynamic field %s shall be an integer field declared before it",
+				name, field.Name)
+		}
+		if sizeField.Offset+sizeField.Len > field.Offset {
+			return fmt.Errorf("size field %s shall precede dynamic field %s",
+				name, field.Name)
+		}
+	}
+	return nil
+}
+
+// shifted returns true if the position of field depends on the data, i.e. a
+// dynamic field is declared before it at the same or a smaller offset.
+func (t *Template) shifted(field *Field) bool {
+	return t.fieldOrder().shifted[field.Name]
+}
+
+// fixedField returns the Field with the given name. It panics if the Template
+// has no such Field or the position of the Field depends on the data.
+func (t *Template) fixedField(fieldName string) *Field {
+	field := t.lookupField(fieldName)
+	if field.dynamic() || t.shifted(field) {
+		panic(fmt.Sprintf("field %s has no fixed position", fieldName))
+	}
+	return field
+}
+
+// recordLayout is the actual layout of a record described by a Template with
+// dynamic fields.
+type recordLayout struct {
+	//fields are the present Fields at their actual position.
+	fields map[string]*Field
+	//absent are the absent optional Fields at the position they would take.
+	absent map[string]*Field
+	//size is the actual size of the record. If complete is false, the data is
+	//too short to read every size field and size is a lower bound of the
+	//actual size that is larger than the length of the data.
+	size     int
+	complete bool
+	//err is set if the size fields describe a record larger than maxInt. The
+	//layout is not complete then.
+	err error
+}
+
+// layout returns the actual layout of the record in data.
+func (t *Template) layout(data []byte) recordLayout {
+	type extent struct {
+		offset uint64
+		len    uint64
+	}
+	l := recordLayout{
+		fields: make(map[string]*Field, len(t.Fields)),
+		absent: make(map[string]*Field),
+	}
+	extents := []extent{}
+	total := uint64(0)
+	for _, field := range t.orderedFields() {
+		shift := uint64(0)
+		for _, e := range extents {
+			if e.offset <= field.Offset {
+				shift += e.len
+			}
+		}
+		if shift == 0 && !field.dynamic() {
+			l.fields[field.Name] = field
+			continue
+		}
+		f := *field
+		f.Offset += shift
+		if field.dynamic() {
+			sizeField := l.fields[field.sizeField()]
+			if sizeField.Offset+sizeField.Len > uint64(len(data)) {
+				l.size = t.Size + int(total)
+				return l
+			}
+			n := sizeField.uintOf(data)
+			if field.PresentIf != "" && n == 0 {
+				l.absent[field.Name] = &f
+				continue
+			}
+			if field.PresentIf == "" {
+				unit := field.unitLen()
+				if unit != 0 && n > (uint64(maxInt)-uint64(t.Size)-total)/unit {
+					l.size = maxInt
+					l.err = fmt.Errorf("field %s: size %d: %w", field.Name, n, ErrRecordTooLarge)
+					return l
+				}
+				f.Len = n * unit
+			}
+			extents = append(extents, extent{offset: field.Offset, len: f.Len})
+			total += f.Len
+		}
+		l.fields[field.Name] = &f
+	}
+	l.size, l.complete = t.Size+int(total), true
+	return l
+}
+
+// fieldsOf returns the Fields of the Template at their actual position in
+// data.
+func (t *Template) fieldsOf(data []byte) map[string]*Field {
+	if !t.dynamic() {
+		return t.Fields
+	}
+	return t.layout(data).fields
+}
+
+//Parse method maps the beginning of data to the Template and returns the
+//resulting Struct and the number of bytes it consumed. For a Template with
+//dynamic fields the size of the record is calculated from its size fields,
+//otherwise it is the Template size. Like New, the returned Struct refers to
+//data.
+//
+//Parse returns a *PartialRecordError if data is shorter than the record and an
+//error wrapping ErrRecordTooLarge if the size fields describe a record that
+//cannot be addressed.
+func (t *Template) Parse(data Valuable) (*Struct, int, error) {
+	value := data.GetValue()
+	size, complete := t.Size, true
+	if t.dynamic() {
+		l := t.layout(value)
+		if l.err != nil {
+			return nil, 0, l.err
+		}
+		size, complete = l.size, l.complete
+	}
+	if !complete || size > len(value) {
+		return nil, 0, &PartialRecordError{
+			Len:  len(value),
+			Size: size,
+		}
+	}
+	return &Struct{
+		Template: t,
+		Value:    value[:size],
+	}, size, nil
+}
+
+// resize replaces the dynamic field of the Template with value and updates its
+// size field. An absent optional field is inserted. The other dynamic fields
+// depending on the same size field are resized too, see updateSize.
+func (s *Struct) resize(original *Field, value Value) {
+	length := uint64(len(value))
+	var n uint64
+	if original.PresentIf != "" {
+		if length != original.Len {
+			panic(fmt.Sprintf("new value size (%d bytes) and field length (%d bytes) mismatch",
+				length, original.Len))
+		}
+		n = 1
+	} else {
+		if length%original.unitLen() != 0 {
+			panic(fmt.Sprintf("new value size (%d bytes) is not a multiple of the element size (%d bytes)",
+				length, original.unitLen()))
+		}
+		n = length / original.unitLen()
+	}
+	l := s.Template.layout(s.Value)
+	if field, present := l.fields[original.Name]; present && field.Len == length {
+		field.updateSlice(s.Value, value)
+		return
+	}
+	sizeField := l.fields[original.sizeField()]
+	checkFits(sizeField, n)
+	sizeField.putUint(s.Value, n)
+	s.resizeDependents(l, n, s.Template.fieldOrder().dependents[sizeField.Name])
+	s.Template.layout(s.Value).fields[original.Name].updateSlice(s.Value, value)
+}
+
+// updateSize writes value into the size field of the dependent dynamic fields
+// and resizes them accordingly, see resizeDependents.
+func (s *Struct) updateSize(sizeField *Field, value Value, dependents []*Field) {
+	l := s.Template.layout(s.Value)
+	sizeField.updateSlice(s.Value, value)
+	s.resizeDependents(l, sizeField.uintOf(s.Value), dependents)
+}
+
+// resizeDependents resizes the dynamic fields depending on a size field whose
+// value changed to n. The layout l is the one before the change. The dynamic
+// fields are truncated or extended with zeros, optional fields are removed or
+// inserted filled with zeros.
+func (s *Struct) resizeDependents(l recordLayout, n uint64, dependents []*Field) {
+	type change struct {
+		offset, oldLen, newLen uint64
+	}
+	changes := make([]change, 0, len(dependents))
+	for _, field := range dependents {
+		var c change
+		if f, present := l.fields[field.Name]; present {
+			c.offset, c.oldLen = f.Offset, f.Len
+		} else {
+			c.offset = l.absent[field.Name].Offset
+		}
+		switch {
+		case field.PresentIf == "":
+			c.newLen = n * field.unitLen()
+		case n != 0:
+			c.newLen = field.Len
+		}
+		changes = append(changes, c)
+	}
+	// The fields are resized from the end of the record, so resizing a field
+	// does not move the ones still to be resized.
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].offset > changes[j].offset
+	})
+	for _, c := range changes {
+		if c.newLen > c.oldLen {
+			s.splice(c.offset+c.oldLen, 0, make(Value, c.newLen-c.oldLen))
+		} else {
+			s.splice(c.offset+c.newLen, c.oldLen-c.newLen, nil)
+		}
+	}
+}
+
+// splice replaces length bytes of the data of s at offset with value. The data
+// is reallocated.
+func (s *Struct) splice(offset, length uint64, value Value) {
+	spliced := make(Value, 0, len(s.Value)-int(length)+len(value))
+	spliced = append(spliced, s.Value[:offset]...)
+	spliced = append(spliced, value...)
+	spliced = append(spliced, s.Value[offset+length:]...)
+	s.Value = spliced
+}
+
+//Array method returns the elements of the array Field indicated by fieldName as
+//Structs. The returned Structs refers to the data of s.
+//
+//Array panics for a non-existing field name or for a Field that is not an
+//array.
+func (s *Struct) Array(fieldName string) *Structs {
+	field := s.resolve(fieldName).field
+	if field.Elem == nil {
+		panic(fmt.Sprintf("field %s is not an array", fieldName))
+	}
+	return field.Elem.Slice(s.Value[field.Offset : field.Offset+field.Len])
+}
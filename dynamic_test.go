@@ -0,0 +1,181 @@
+package bmstruct
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dynamic fields", func() {
+	var point, tmpl *Template
+
+	BeforeEach(func() {
+		point = NewTemplate(-1,
+			Uint8Field("x", 0),
+			Uint8Field("y", 1),
+		)
+		tmpl = NewTemplate(-1,
+			Uint16Field("len", 0),
+			Uint8Field("count", 2),
+			DynamicField("name", 3, "len"),
+			ArrayField("points", 3, "count", point),
+			Uint16Field("crc", 3),
+		)
+	})
+	It("should have the minimum size", func() {
+		Expect(tmpl.Size).To(Equal(5))
+		s := tmpl.Empty()
+		Expect(s.Lookup("name")).To(BeEmpty())
+		Expect(s.Lookup("crc")).To(Equal(Value{0, 0}))
+	})
+	Describe("parsing data", func() {
+		data := Value{3, 0, 2, 'a', 'b', 'c', 1, 2, 3, 4, 0xcd, 0xab, 0xff}
+		It("should calculate the size of the record", func() {
+			s, n, err := tmpl.Parse(data)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(12))
+			Expect(s.Value).To(Equal(data[:12]))
+			Expect(s.Lookup("name")).To(Equal(Value("abc")))
+			Expect(s.Lookup("points")).To(Equal(Value{1, 2, 3, 4}))
+			Expect(s.Lookup("crc").Uint16()).To(Equal(uint16(0xabcd)))
+		})
+		It("should return the elements of arrays", func() {
+			s, _, err := tmpl.Parse(data)
+			Expect(err).NotTo(HaveOccurred())
+			points := s.Array("points")
+			Expect(points.Count()).To(Equal(uint32(2)))
+			Expect(points.Nth(1).Lookup("y").Uint8()).To(Equal(uint8(4)))
+			Expect(func() {
+				s.Array("name")
+			}).To(Panic())
+		})
+		It("should report partial records", func() {
+			var partial *PartialRecordError
+			_, _, err := tmpl.Parse(data[:8])
+			Expect(errors.As(err, &partial)).To(BeTrue())
+			Expect(partial.Size).To(Equal(12))
+			_, _, err = tmpl.Parse(data[:1])
+			Expect(errors.As(err, &partial)).To(BeTrue())
+			Expect(partial.Size).To(Equal(5))
+		})
+		It("should report records larger than the largest int", func() {
+			t := NewTemplate(-1, Uint64Field("len", 0), DynamicField("body", 8, "len"))
+			huge := Uint64(0x8000000000000008).GetValue()
+			_, _, err := t.Parse(append(huge, 1, 2, 3))
+			Expect(errors.Is(err, ErrRecordTooLarge)).To(BeTrue())
+			Expect(func() { t.New(append(huge, 1, 2, 3)) }).To(Panic())
+			elem := NewTemplate(-1, Uint32Field("v", 0))
+			t = NewTemplate(-1, Uint64Field("count", 0), ArrayField("items", 8, "count", elem))
+			_, _, err = t.Parse(append(Uint64(1<<62).GetValue(), 1, 2, 3, 4))
+			Expect(errors.Is(err, ErrRecordTooLarge)).To(BeTrue())
+		})
+		It("should work for fixed size templates", func() {
+			s, n, err := point.Parse(Value{1, 2, 3})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(2))
+			Expect(s.Lookup("y").Uint8()).To(Equal(uint8(2)))
+		})
+		It("should check the size in New", func() {
+			Expect(tmpl.New(data[:12]).Lookup("crc").Uint16()).To(Equal(uint16(0xabcd)))
+			Expect(func() {
+				tmpl.New(data)
+			}).To(Panic())
+		})
+	})
+	Describe("updating dynamic fields", func() {
+		It("should resize the record and update the size fields", func() {
+			s := tmpl.Empty()
+			s.Update("crc", Uint16(0x1234))
+			s.Update("name", Value("hello"))
+			s.Update("points", Value{7, 8})
+			Expect(s.Value).To(Equal(Value{5, 0, 1, 'h', 'e', 'l', 'l', 'o', 7, 8, 0x34, 0x12}))
+			update := s.UpdateFunc("name")
+			update(Value("hi"))
+			Expect(s.Lookup("len").Uint16()).To(Equal(uint16(2)))
+			Expect(s.Lookup("crc").Uint16()).To(Equal(uint16(0x1234)))
+			parsed, n, err := tmpl.Parse(s.Value)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(9))
+			Expect(parsed.Lookup("points")).To(Equal(Value{7, 8}))
+		})
+		It("should resize the dynamic fields when their size fields are updated", func() {
+			s := NewTemplate(-1,
+				Uint8Field("len", 0),
+				DynamicField("data", 1, "len"),
+				Uint8Field("tail", 1),
+			).Empty()
+			Expect(s.Set("data", Value{1, 2, 3})).To(Succeed())
+			s.Update("tail", Value{9})
+			s.Update("len", Value{6})
+			Expect(s.Value).To(Equal(Value{6, 1, 2, 3, 0, 0, 0, 9}))
+			Expect(s.Lookup("tail")).To(Equal(Value{9}))
+			Expect(s.Set("len", 2)).To(Succeed())
+			Expect(s.Value).To(Equal(Value{2, 1, 2, 9}))
+			s.UpdateFunc("len")(Value{0})
+			Expect(s.Value).To(Equal(Value{0, 9}))
+
+			s = tmpl.Empty()
+			s.Update("crc", Uint16(0x1234))
+			s.Update("points", Value{1, 2})
+			s.Update("count", Value{3})
+			Expect(s.Lookup("points")).To(Equal(Value{1, 2, 0, 0, 0, 0}))
+			Expect(s.Lookup("crc").Uint16()).To(Equal(uint16(0x1234)))
+		})
+		It("should resize the dynamic fields sharing a size field together", func() {
+			t := NewTemplate(-1,
+				Uint8Field("len", 0),
+				DynamicField("a", 1, "len"),
+				DynamicField("b", 1, "len"),
+				Uint8Field("tail", 1),
+			)
+			s := t.New(Value{1, 0xaa, 0xbb, 0xcc})
+			s.Update("a", Value{1, 2})
+			Expect(s.Value).To(Equal(Value{2, 1, 2, 0xbb, 0, 0xcc}))
+			s.Update("b", Value{3})
+			Expect(s.Value).To(Equal(Value{1, 1, 3, 0xcc}))
+		})
+		It("should report data inconsistent with the size fields", func() {
+			s := NewTemplate(-1,
+				Uint8Field("len", 0),
+				DynamicField("data", 1, "len"),
+				Uint8Field("tail", 1),
+			).Empty()
+			s.Value[0] = 10
+			_, err := s.LookupE("tail")
+			Expect(err).To(MatchError(ContainSubstring("shorter than the record")))
+		})
+		It("should panic for incorrect sizes", func() {
+			s := tmpl.Empty()
+			Expect(func() {
+				s.Update("points", Value{1, 2, 3})
+			}).To(Panic())
+			Expect(func() {
+				s.Update("points", make(Value, 2*256))
+			}).To(Panic())
+		})
+	})
+	It("should panic for invalid definitions", func() {
+		Expect(func() {
+			NewTemplate(-1, DynamicField("body", 0, "len"))
+		}).To(Panic())
+		Expect(func() {
+			NewTemplate(-1, DynamicField("body", 2, "len"), Uint16Field("len", 0))
+		}).To(Panic())
+		Expect(func() {
+			NewTemplate(-1, Uint16Field("len", 0), DynamicField("body", 1, "len"))
+		}).To(Panic())
+		Expect(func() {
+			tmpl.Field("nested", 0)
+		}).To(Panic())
+		Expect(func() {
+			tmpl.Slice(Value{})
+		}).To(Panic())
+		Expect(func() {
+			tmpl.Accessor("crc")
+		}).To(Panic())
+		Expect(tmpl.Accessor("count").Len()).To(Equal(uint64(1)))
+		_, err := tmpl.Compile("crc == 0")
+		Expect(err).To(HaveOccurred())
+	})
+})
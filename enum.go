@@ -0,0 +1,171 @@
+package bmstruct
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+//ErrUnknownName is returned (wrapped) by UpdateName and Set when the given name
+//is not defined by the Enum of the field.
+var ErrUnknownName = errors.New("unknown enum name")
+
+//Enum maps the values of an integer Field to symbolic names, e.g. the
+//ethertypes 0x0800 and 0x0806 to "IPv4" and "ARP". The JSON representation of
+//an Enum is an object of the values keyed by the names.
+type Enum struct {
+	names  map[uint64]string
+	values map[string]uint64
+}
+
+//NewEnum function creates a new Enum from the values keyed by their names.
+//
+//NewEnum panics if two names have the same value or a name is a number, which
+//would be ambiguous with the unknown values, see LookupName.
+func NewEnum(values map[string]uint64) *Enum {
+	e, err := newEnum(values)
+	if err != nil {
+		panic(err.Error())
+	}
+	return e
+}
+
+// newEnum creates a new Enum like NewEnum, but it returns an error instead of
+// panicking.
+func newEnum(values map[string]uint64) (*Enum, error) {
+	e := &Enum{
+		names:  make(map[uint64]string, len(values)),
+		values: make(map[string]uint64, len(values)),
+	}
+	for name, value := range values {
+		if other, found := e.names[value]; found {
+			return nil, fmt.Errorf("names %s and %s have the same value %d", other, name, value)
+		}
+		if _, err := strconv.ParseUint(name, 0, 64); err == nil || name == "" {
+			return nil, fmt.Errorf("invalid enum name %q", name)
+		}
+		e.names[value] = name
+		e.values[name] = value
+	}
+	return e, nil
+}
+
+//Name method returns the name of the value and true or an empty string and
+//false if the value has no name.
+func (e *Enum) Name(value uint64) (string, bool) {
+	name, found := e.names[value]
+	return name, found
+}
+
+//Value method returns the value of the name and true or 0 and false if the
+//name is unknown.
+func (e *Enum) Value(name string) (uint64, bool) {
+	value, found := e.values[name]
+	return value, found
+}
+
+//Names method returns the names of the Enum ordered by their values.
+func (e *Enum) Names() []string {
+	names := make([]string, 0, len(e.values))
+	for name := range e.values {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return e.values[names[i]] < e.values[names[j]]
+	})
+	return names
+}
+
+//MarshalJSON implements the json.Marshaler interface.
+func (e *Enum) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.values)
+}
+
+//UnmarshalJSON implements the json.Unmarshaler interface.
+func (e *Enum) UnmarshalJSON(b []byte) error {
+	var values map[string]uint64
+	if err := json.Unmarshal(b, &values); err != nil {
+		return err
+	}
+	parsed, err := newEnum(values)
+	if err != nil {
+		return err
+	}
+	*e = *parsed
+	return nil
+}
+
+//EnumField function returns a copy of the given integer field or bit field with
+//the Enum attached. The values of the Enum are compared to the unsigned value
+//of the field, i.e. to the bit pattern of signed fields.
+//
+//EnumField panics if the field is not an integer.
+func EnumField(field *Field, enum *Enum) *Field {
+	if !field.isInteger() {
+		panic(fmt.Sprintf("field %s of kind %s cannot be an enum", field.Name, field.Kind))
+	}
+	named := *field
+	named.Enum = enum
+	return &named
+}
+
+// enumField returns the Field indicated by fieldName and whether it is
+// present. It panics if the field is not an enum.
+func (s *Struct) enumField(fieldName string) (*Field, bool) {
+	field, present := s.resolvePresent(fieldName), true
+	if field == nil {
+		field, present = s.Template.Fields[fieldName], false
+	}
+	if field.Enum == nil {
+		panic(fmt.Sprintf("field %s is not an enum", fieldName))
+	}
+	return field, present
+}
+
+//LookupName method returns the name of the value of the enum field indicated
+//by fieldName. For a value without a name it returns the value as a decimal
+//number, which UpdateName accepts too. LookupName returns an empty string for
+//an absent optional field.
+//
+//LookupName panics for a non-existing field name or for a field without Enum.
+func (s *Struct) LookupName(fieldName string) string {
+	field, present := s.enumField(fieldName)
+	if !present {
+		return ""
+	}
+	value := field.uintOf(s.Value)
+	if name, found := field.Enum.Name(value); found {
+		return name
+	}
+	return strconv.FormatUint(value, 10)
+}
+
+//UpdateName method changes the enum field indicated by fieldName to the value
+//of the given name, like Set does for a string. Besides the names of the Enum,
+//it accepts numbers in the syntax of Go integer literals, e.g. "2054" or
+//"0x0806", for the values without a name.
+//
+//UpdateName returns an error wrapping ErrUnknownName for an unknown name and an
+//error wrapping ErrOverflow if the number does not fit into the field. It
+//panics for a non-existing field name or for a field without Enum.
+func (s *Struct) UpdateName(fieldName, name string) error {
+	s.enumField(fieldName)
+	return s.Set(fieldName, name)
+}
+
+// encodeName returns the Value of the enum field for the given name or number.
+func (f *Field) encodeName(name string) (Value, error) {
+	u, found := f.Enum.Value(name)
+	if !found {
+		var err error
+		if u, err = strconv.ParseUint(name, 0, 64); err != nil {
+			return nil, fmt.Errorf("%q: %w", name, ErrUnknownName)
+		}
+	}
+	if bits := f.bits(); bits < 64 && u >= uint64(1)<<bits {
+		return nil, fmt.Errorf("%s: %w", name, ErrOverflow)
+	}
+	return f.uintValue(u), nil
+}
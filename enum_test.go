@@ -0,0 +1,95 @@
+package bmstruct
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Enum fields", func() {
+	var tmpl *Template
+	var s *Struct
+
+	BeforeEach(func() {
+		ethertypes := NewEnum(map[string]uint64{
+			"IPv4": 0x0800,
+			"ARP":  0x0806,
+			"IPv6": 0x86dd,
+		})
+		states := NewEnum(map[string]uint64{"IDLE": 0, "BUSY": 1})
+		tmpl = NewTemplate(-1,
+			EnumField(Uint16Field("ethertype", 0), ethertypes),
+			EnumField(BitField("state", 2, 0, 2), states),
+			Uint8Field("plain", 3),
+		)
+		s = tmpl.Empty()
+	})
+	It("should map the values to names", func() {
+		Expect(s.LookupName("state")).To(Equal("IDLE"))
+		Expect(s.UpdateName("ethertype", "ARP")).To(Succeed())
+		Expect(s.Get("ethertype")).To(Equal(uint16(0x0806)))
+		Expect(s.LookupName("ethertype")).To(Equal("ARP"))
+		Expect(s.Set("state", "BUSY")).To(Succeed())
+		Expect(s.Get("state")).To(Equal(uint8(1)))
+		Expect(s.Set("ethertype", 0x86dd)).To(Succeed())
+		Expect(s.LookupName("ethertype")).To(Equal("IPv6"))
+	})
+	It("should handle unknown values", func() {
+		Expect(s.Set("ethertype", 0x88cc)).To(Succeed())
+		Expect(s.LookupName("ethertype")).To(Equal("35020"))
+		Expect(s.UpdateName("ethertype", "0x0800")).To(Succeed())
+		Expect(s.LookupName("ethertype")).To(Equal("IPv4"))
+		Expect(s.UpdateName("state", "3")).To(Succeed())
+		Expect(s.LookupName("state")).To(Equal("3"))
+		Expect(errors.Is(s.UpdateName("ethertype", "LLDP"), ErrUnknownName)).To(BeTrue())
+		Expect(errors.Is(s.UpdateName("state", "4"), ErrOverflow)).To(BeTrue())
+	})
+	It("should panic for fields without enum", func() {
+		Expect(func() { s.LookupName("plain") }).To(Panic())
+		Expect(func() { s.UpdateName("plain", "1") }).To(Panic())
+		Expect(s.Set("plain", "1")).NotTo(Succeed())
+		Expect(func() { EnumField(StringField("s", 0, 4, 0), NewEnum(nil)) }).To(Panic())
+	})
+	It("should not change the field given to EnumField", func() {
+		plain := Uint8Field("plain", 0)
+		Expect(EnumField(plain, NewEnum(nil)).Enum).NotTo(BeNil())
+		Expect(plain.Enum).To(BeNil())
+	})
+	It("should reject ambiguous enums", func() {
+		Expect(func() { NewEnum(map[string]uint64{"A": 1, "B": 1}) }).To(Panic())
+		Expect(func() { NewEnum(map[string]uint64{"0x10": 1}) }).To(Panic())
+	})
+	It("should list the names by value", func() {
+		Expect(tmpl.Fields["ethertype"].Enum.Names()).To(Equal([]string{"IPv4", "ARP", "IPv6"}))
+		value, found := tmpl.Fields["ethertype"].Enum.Value("ARP")
+		Expect(found).To(BeTrue())
+		Expect(value).To(Equal(uint64(0x0806)))
+		_, found = tmpl.Fields["ethertype"].Enum.Name(1)
+		Expect(found).To(BeFalse())
+	})
+	It("should be represented by names in the JSON of the Struct", func() {
+		Expect(s.UpdateName("ethertype", "ARP")).To(Succeed())
+		s.Update("state", Value{3})
+		b, err := json.Marshal(s)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(ContainSubstring(`"fields":{"ethertype":"ARP","state":3,"plain":0}`))
+		var decoded Struct
+		Expect(json.Unmarshal(b, &decoded)).To(Succeed())
+		Expect(decoded.Value).To(Equal(s.Value))
+		b = []byte(strings.Replace(string(b), `"ethertype":"ARP"`, `"ethertype":"IPv6"`, 1))
+		Expect(json.Unmarshal(b, &decoded)).To(Succeed())
+		Expect(decoded.LookupName("ethertype")).To(Equal("IPv6"))
+		b = []byte(strings.Replace(string(b), `"ethertype":"IPv6"`, `"ethertype":"IPX"`, 1))
+		Expect(errors.Is(json.Unmarshal(b, &decoded), ErrUnknownName)).To(BeTrue())
+	})
+	It("should represent the Enum as an object in JSON", func() {
+		b, err := json.Marshal(tmpl.Fields["ethertype"].Enum)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`{"ARP":2054,"IPv4":2048,"IPv6":34525}`))
+		Expect(json.Unmarshal([]byte(`{"A":1,"B":1}`), &Enum{})).NotTo(Succeed())
+		Expect(json.Unmarshal([]byte(`{"0x10":1}`), &Enum{})).To(MatchError(`invalid enum name "0x10"`))
+	})
+})
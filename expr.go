@@ -0,0 +1,465 @@
+package bmstruct
+
+import (
+	"fmt"
+	"strconv"
+)
+
+//Expr is a compiled expression over the fields of a Template. It can be used
+//for filtering Structs, e.g.
+//
+//  e, err := t.Compile("len > 100 && flags.syn == 1")
+//
+//The expression language supports integer literals (decimal, 0x hexadecimal
+//and 0 octal), field names, parentheses and the following operators in
+//ascending order of precedence:
+//
+//  ||
+//  &&
+//  == != < <= > >=
+//  |
+//  &
+//  + -
+//  * / %
+//  ! - (unary)
+//
+//All values are int64, logical operators treat 0 as false and everything else
+//as true. The result of a comparison or a logical operator is 1 or 0. Only
+//integer fields (including bit fields) and bool fields can be referred to whose
+//position does not depend on a dynamic field. A bool field is 1 if it is true
+//and 0 otherwise.
+type Expr struct {
+	src  string
+	eval evalFn
+}
+
+type evalFn func(data []byte) int64
+
+//Compile method compiles an expression against the fields of the Template.
+//Compile returns an error if the expression is invalid or refers to a field
+//that does not exist or is neither an integer nor a bool field.
+func (t *Template) Compile(expr string) (*Expr, error) {
+	p := &exprParser{
+		src:      expr,
+		template: t,
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	eval, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %q", p.tok.text)
+	}
+	return &Expr{
+		src:  expr,
+		eval: eval,
+	}, nil
+}
+
+//String method returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+//Eval method evaluates the expression on the given Struct. The Struct shall
+//have the same Template that was used for compiling the expression.
+func (e *Expr) Eval(s *Struct) int64 {
+	return e.eval(s.Value)
+}
+
+//Match method returns true if the expression evaluates to a non-zero value on
+//the given Struct.
+func (e *Expr) Match(s *Struct) bool {
+	return e.eval(s.Value) != 0
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokNumber
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+type exprParser struct {
+	src      string
+	pos      int
+	tok      token
+	template *Template
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("expression %q at %d: %s",
+		p.src, p.tok.pos, fmt.Sprintf(format, args...))
+}
+
+var exprOps = []string{
+	"||", "&&", "==", "!=", "<=", ">=",
+	"<", ">", "|", "&", "+", "-", "*", "/", "%", "!", "(", ")",
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || c == '.' || (c >= '0' && c <= '9')
+}
+
+func (p *exprParser) next() error {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+	start := p.pos
+	p.tok = token{pos: start}
+	if p.pos == len(p.src) {
+		p.tok.kind = tokEOF
+		return nil
+	}
+	c := p.src[p.pos]
+	switch {
+	case c >= '0' && c <= '9':
+		for p.pos < len(p.src) && isIdentChar(p.src[p.pos]) {
+			p.pos++
+		}
+		p.tok.kind = tokNumber
+	case isIdentStart(c):
+		for p.pos < len(p.src) && isIdentChar(p.src[p.pos]) {
+			p.pos++
+		}
+		p.tok.kind = tokIdent
+	default:
+		for _, op := range exprOps {
+			if len(p.src)-p.pos >= len(op) && p.src[p.pos:p.pos+len(op)] == op {
+				p.pos += len(op)
+				p.tok.kind = tokOp
+				break
+			}
+		}
+		if p.tok.kind != tokOp {
+			return p.errorf("unexpected character %q", c)
+		}
+	}
+	p.tok.text = p.src[start:p.pos]
+	return nil
+}
+
+func (p *exprParser) isOp(ops ...string) bool {
+	if p.tok.kind != tokOp {
+		return false
+	}
+	for _, op := range ops {
+		if p.tok.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func binaryOp(op string, x, y evalFn) evalFn {
+	switch op {
+	case "||":
+		return func(data []byte) int64 {
+			return boolInt(x(data) != 0 || y(data) != 0)
+		}
+	case "&&":
+		return func(data []byte) int64 {
+			return boolInt(x(data) != 0 && y(data) != 0)
+		}
+	case "==":
+		return func(data []byte) int64 { return boolInt(x(data) == y(data)) }
+	case "!=":
+		return func(data []byte) int64 { return boolInt(x(data) != y(data)) }
+	case "<":
+		return func(data []byte) int64 { return boolInt(x(data) < y(data)) }
+	case "<=":
+		return func(data []byte) int64 { return boolInt(x(data) <= y(data)) }
+	case ">":
+		return func(data []byte) int64 { return boolInt(x(data) > y(data)) }
+	case ">=":
+		return func(data []byte) int64 { return boolInt(x(data) >= y(data)) }
+	case "|":
+		return func(data []byte) int64 { return x(data) | y(data) }
+	case "&":
+		return func(data []byte) int64 { return x(data) & y(data) }
+	case "+":
+		return func(data []byte) int64 { return x(data) + y(data) }
+	case "-":
+		return func(data []byte) int64 { return x(data) - y(data) }
+	case "*":
+		return func(data []byte) int64 { return x(data) * y(data) }
+	case "/":
+		return func(data []byte) int64 {
+			d := y(data)
+			if d == 0 {
+				return 0
+			}
+			return x(data) / d
+		}
+	case "%":
+		return func(data []byte) int64 {
+			d := y(data)
+			if d == 0 {
+				return 0
+			}
+			return x(data) % d
+		}
+	}
+	panic(fmt.Sprintf("unknown operator %s", op))
+}
+
+// parseBinary parses a left-associative chain of operands separated by the
+// given operators.
+func (p *exprParser) parseBinary(operand func() (evalFn, error),
+	ops ...string) (evalFn, error) {
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(ops...) {
+		op := p.tok.text
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		x = binaryOp(op, x, y)
+	}
+	return x, nil
+}
+
+func (p *exprParser) parseOr() (evalFn, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *exprParser) parseAnd() (evalFn, error) {
+	return p.parseBinary(p.parseComparison, "&&")
+}
+
+func (p *exprParser) parseComparison() (evalFn, error) {
+	x, err := p.parseBitOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOp("==", "!=", "<", "<=", ">", ">=") {
+		op := p.tok.text
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		y, err := p.parseBitOr()
+		if err != nil {
+			return nil, err
+		}
+		x = binaryOp(op, x, y)
+	}
+	return x, nil
+}
+
+func (p *exprParser) parseBitOr() (evalFn, error) {
+	return p.parseBinary(p.parseBitAnd, "|")
+}
+
+func (p *exprParser) parseBitAnd() (evalFn, error) {
+	return p.parseBinary(p.parseSum, "&")
+}
+
+func (p *exprParser) parseSum() (evalFn, error) {
+	return p.parseBinary(p.parseProduct, "+", "-")
+}
+
+func (p *exprParser) parseProduct() (evalFn, error) {
+	return p.parseBinary(p.parseUnary, "*", "/", "%")
+}
+
+func (p *exprParser) parseUnary() (evalFn, error) {
+	if !p.isOp("!", "-") {
+		return p.parsePrimary()
+	}
+	op := p.tok.text
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if op == "!" {
+		return func(data []byte) int64 { return boolInt(x(data) == 0) }, nil
+	}
+	return func(data []byte) int64 { return -x(data) }, nil
+}
+
+func (p *exprParser) parsePrimary() (evalFn, error) {
+	switch {
+	case p.tok.kind == tokNumber:
+		n, err := strconv.ParseInt(p.tok.text, 0, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", p.tok.text)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		return func([]byte) int64 { return n }, nil
+	case p.tok.kind == tokIdent:
+		field, found := p.template.Fields[p.tok.text]
+		if !found {
+			return nil, p.errorf("field name %s not found in template", p.tok.text)
+		}
+		if !field.isInteger() && field.Kind != BoolKind {
+			return nil, p.errorf("field %s of kind %s is not an integer",
+				field.Name, field.Kind)
+		}
+		if p.template.shifted(field) {
+			return nil, p.errorf("field %s has no fixed position", field.Name)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if !field.isInteger() {
+			return func(data []byte) int64 {
+				if field.uintOf(data) != 0 {
+					return 1
+				}
+				return 0
+			}, nil
+		}
+		return field.int64Of, nil
+	case p.isOp("("):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isOp(")") {
+			return nil, p.errorf("missing )")
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		return x, nil
+	case p.tok.kind == tokEOF:
+		return nil, p.errorf("unexpected end of expression")
+	default:
+		return nil, p.errorf("unexpected %q", p.tok.text)
+	}
+}
+
+func (ss *Structs) matching(expr string) ([]int, error) {
+	e, err := ss.Template.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	indices := []int{}
+	for n := 0; n < int(ss.Count()); n++ {
+		if e.eval(ss.row(n)) != 0 {
+			indices = append(indices, n)
+		}
+	}
+	return indices, nil
+}
+
+//FilterIndices method returns the indices of the Struct objects for which the
+//expression is true. See Expr for the syntax of the expression.
+func (ss *Structs) FilterIndices(expr string) ([]int, error) {
+	return ss.matching(expr)
+}
+
+//Filter method returns a new Structs with a copy of the Struct objects for
+//which the expression is true. See Expr for the syntax of the expression.
+func (ss *Structs) Filter(expr string) (*Structs, error) {
+	indices, err := ss.matching(expr)
+	if err != nil {
+		return nil, err
+	}
+	value := make([]byte, 0, len(indices)*ss.Template.Size)
+	for _, n := range indices {
+		value = append(value, ss.row(n)...)
+	}
+	return &Structs{
+		Template: ss.Template,
+		Value:    value,
+	}, nil
+}
+
+//CountWhere method returns the number of Struct objects for which the
+//expression is true. See Expr for the syntax of the expression.
+func (ss *Structs) CountWhere(expr string) (int, error) {
+	indices, err := ss.matching(expr)
+	if err != nil {
+		return 0, err
+	}
+	return len(indices), nil
+}
+
+func (ss *Structs) integerField(fieldName string) *Field {
+	field := ss.Template.lookupField(fieldName)
+	if !field.isInteger() {
+		panic(fmt.Sprintf("field %s of kind %s is not an integer",
+			field.Name, field.Kind))
+	}
+	return field
+}
+
+//Sum method returns the sum of the integer field indicated by fieldName over
+//all Struct objects. The values are added as int64.
+//
+//Sum panics for a non-existing or non-integer field.
+func (ss *Structs) Sum(fieldName string) int64 {
+	field := ss.integerField(fieldName)
+	sum := int64(0)
+	for n := 0; n < int(ss.Count()); n++ {
+		sum += field.int64Of(ss.row(n))
+	}
+	return sum
+}
+
+//Min method returns the smallest value of the integer field indicated by
+//fieldName as int64.
+//
+//Min panics for a non-existing or non-integer field and for an empty Structs.
+func (ss *Structs) Min(fieldName string) int64 {
+	return ss.extreme(fieldName, -1)
+}
+
+//Max method returns the largest value of the integer field indicated by
+//fieldName as int64.
+//
+//Max panics for a non-existing or non-integer field and for an empty Structs.
+func (ss *Structs) Max(fieldName string) int64 {
+	return ss.extreme(fieldName, 1)
+}
+
+func (ss *Structs) extreme(fieldName string, sign int) int64 {
+	field := ss.integerField(fieldName)
+	if ss.Count() == 0 {
+		panic("no structs to aggregate")
+	}
+	best := 0
+	for n := 1; n < int(ss.Count()); n++ {
+		if field.compare(ss.row(n), ss.row(best))*sign > 0 {
+			best = n
+		}
+	}
+	return field.int64Of(ss.row(best))
+}
@@ -0,0 +1,109 @@
+package bmstruct
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Expr", func() {
+	var tmpl *Template
+	var ss *Structs
+
+	BeforeEach(func() {
+		tmpl = NewTemplate(4,
+			Uint16Field("len", 0),
+			Int8Field("delta", 2),
+			BitField("flags.syn", 3, 1, 1),
+			BitField("flags.ack", 3, 4, 1),
+		)
+		ss = tmpl.Slice(Value{
+			50, 0, 1, 0x02,
+			200, 0, 0xfe, 0x12,
+			150, 0, 3, 0x10,
+			0, 1, 0xff, 0x02,
+		})
+	})
+	Describe("compiling an expression", func() {
+		It("should evaluate fields and literals", func() {
+			e, err := tmpl.Compile("len * 2 + delta")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(e.Eval(ss.Nth(1))).To(Equal(int64(398)))
+			Expect(e.String()).To(Equal("len * 2 + delta"))
+		})
+		It("should respect operator precedence", func() {
+			e, err := tmpl.Compile("1 + 2 * 3 == 7 && !(0x10 & 1) || 0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(e.Match(ss.Nth(0))).To(BeTrue())
+			e, err = tmpl.Compile("-delta % 3 | 4")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(e.Eval(ss.Nth(1))).To(Equal(int64(6)))
+		})
+		It("should report unknown fields", func() {
+			_, err := tmpl.Compile("length > 100")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should report non-integer fields", func() {
+			t := NewTemplate(-1, (&Template{Size: 12}).Field("raw", 0))
+			_, err := t.Compile("raw == 1")
+			Expect(err).To(HaveOccurred())
+			t = NewTemplate(-1, Float32Field("f32", 0))
+			_, err = t.Compile("f32 == 1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should report syntax errors", func() {
+			for _, expr := range []string{
+				"", "len >", "(len > 1", "len > 1)", "len # 2", "1.5", "len len",
+			} {
+				_, err := tmpl.Compile(expr)
+				Expect(err).To(HaveOccurred(), expr)
+			}
+		})
+	})
+	Describe("filtering Structs", func() {
+		It("should return the matching indices", func() {
+			Expect(ss.FilterIndices("len > 100 && flags.syn == 1")).To(Equal([]int{1, 3}))
+			Expect(ss.FilterIndices("flags.ack")).To(Equal([]int{1, 2}))
+			Expect(ss.FilterIndices("delta < 0")).To(Equal([]int{1, 3}))
+			Expect(ss.FilterIndices("len > 1000")).To(BeEmpty())
+		})
+		It("should return a new Structs", func() {
+			filtered, err := ss.Filter("flags.syn == 1 && len > 100")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filtered.Count()).To(Equal(uint32(2)))
+			Expect(filtered.Column("len")).To(Equal([]uint16{200, 256}))
+			filtered.SetColumn("len", []uint16{0, 0})
+			Expect(ss.Column("len")).To(Equal([]uint16{50, 200, 150, 256}))
+		})
+		It("should count the matching Structs", func() {
+			Expect(ss.CountWhere("len >= 150")).To(Equal(3))
+		})
+		It("should report invalid expressions", func() {
+			_, err := ss.Filter("no-such-field")
+			Expect(err).To(HaveOccurred())
+			_, err = ss.FilterIndices("(")
+			Expect(err).To(HaveOccurred())
+			_, err = ss.CountWhere(")")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("aggregating fields", func() {
+		It("should return the sum", func() {
+			Expect(ss.Sum("len")).To(Equal(int64(656)))
+			Expect(ss.Sum("delta")).To(Equal(int64(1)))
+		})
+		It("should return the minimum and maximum", func() {
+			Expect(ss.Min("len")).To(Equal(int64(50)))
+			Expect(ss.Max("len")).To(Equal(int64(256)))
+			Expect(ss.Min("delta")).To(Equal(int64(-2)))
+			Expect(ss.Max("delta")).To(Equal(int64(3)))
+			Expect(ss.Max("flags.ack")).To(Equal(int64(1)))
+		})
+		It("should panic for empty Structs", func() {
+			empty := tmpl.Slice(Value{})
+			Expect(empty.Sum("len")).To(Equal(int64(0)))
+			Expect(func() {
+				empty.Min("len")
+			}).To(Panic())
+		})
+	})
+})
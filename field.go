@@ -1,9 +1,111 @@
 package bmstruct
 
 import (
+	"bytes"
+	"fmt"
+	"math"
 	"reflect"
 )
 
+//Kind tells how the bytes of a Field shall be interpreted. The zero Kind is
+//BytesKind, i.e. the Field is a plain byte slice without any interpretation.
+type Kind uint8
+
+//The Kinds of the Fields created by the Field constructors of this package.
+const (
+	BytesKind Kind = iota
+	Uint8Kind
+	Int8Kind
+	Uint16Kind
+	Int16Kind
+	Uint32Kind
+	Int32Kind
+	Uint64Kind
+	Int64Kind
+	UintKind
+	IntKind
+	UintptrKind
+	Float32Kind
+	Float64Kind
+	VariantKind
+	StringKind
+	ZonedDecimalKind
+	PackedDecimalKind
+	NumericTextKind
+	BoolKind
+	Uint128Kind
+	BigUintKind
+	BigIntKind
+)
+
+var kindNames = map[Kind]string{
+	BytesKind:         "bytes",
+	Uint8Kind:         "uint8",
+	Int8Kind:          "int8",
+	Uint16Kind:        "uint16",
+	Int16Kind:         "int16",
+	Uint32Kind:        "uint32",
+	Int32Kind:         "int32",
+	Uint64Kind:        "uint64",
+	Int64Kind:         "int64",
+	UintKind:          "uint",
+	IntKind:           "int",
+	UintptrKind:       "uintptr",
+	Float32Kind:       "float32",
+	Float64Kind:       "float64",
+	VariantKind:       "variant",
+	StringKind:        "string",
+	ZonedDecimalKind:  "zoned",
+	PackedDecimalKind: "packed",
+	NumericTextKind:   "numeric-text",
+	BoolKind:          "bool",
+	Uint128Kind:       "uint128",
+	BigUintKind:       "big-uint",
+	BigIntKind:        "big-int",
+}
+
+//String method returns the name of the Kind, e.g. "uint16".
+func (k Kind) String() string {
+	if name, found := kindNames[k]; found {
+		return name
+	}
+	return fmt.Sprintf("kind(%d)", uint8(k))
+}
+
+//MarshalText implements the encoding.TextMarshaler interface so that a Kind is
+//represented by its name in JSON.
+func (k Kind) MarshalText() ([]byte, error) {
+	if _, found := kindNames[k]; !found {
+		return nil, fmt.Errorf("unknown kind %d", uint8(k))
+	}
+	return []byte(k.String()), nil
+}
+
+//UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (k *Kind) UnmarshalText(text []byte) error {
+	for kind, name := range kindNames {
+		if name == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown kind %q", text)
+}
+
+//Integer method returns true if the Kind is a signed or unsigned integer.
+func (k Kind) Integer() bool {
+	return k >= Uint8Kind && k <= UintptrKind
+}
+
+//Signed method returns true if the Kind is a signed integer.
+func (k Kind) Signed() bool {
+	switch k {
+	case Int8Kind, Int16Kind, Int32Kind, Int64Kind, IntKind:
+		return true
+	}
+	return false
+}
+
 //Field represents a smaller fraction of a byte slice or Value that in itself
 //contains a meaningful value, like an int or string.
 //
@@ -39,19 +141,58 @@ import (
 //       ^^^^
 //  2:  00010100
 //  3:  00001000
+//
+//A Field created by the Field method of a Template refers to that Template, so
+//the Fields of the nested Template can be reached, e.g. by Template.Walk.
+//
+//The length of a dynamic Field, created by DynamicField or ArrayField, is
+//stored in another Field named by LenField or CountField. An optional Field,
+//created by OptionalField, is present only if the Field named by PresentIf is
+//not zero.
+//
+//Prefix, Padding, Truncate and Charset are the options of string Fields, see
+//StringField and PascalStringField. Digits, Scale and Signed are the options of
+//decimal Fields, see ZonedDecimalField and PackedDecimalField. Base, Padding
+//and Terminator are the options of numeric text Fields, see NumericTextField.
+//Enum names the values of integer Fields, see EnumField, and Flags names their
+//bits, see FlagsField. Strict is the option of bool Fields, see BoolField, and
+//BigEndian is the option of integer, floating point, 128-bit and big integer
+//Fields, see UintNField and Uint128Field.
 type Field struct {
-	Name           string `json:"name"`
-	Offset         uint64 `json:"offset"`
-	Len            uint64 `json:"length"`
-	BitFieldOffset uint8  `json:"bf-offset,omitempty"`
-	BitFieldLen    uint8  `json:"bf-len,omitempty"`
+	Name           string    `json:"name"`
+	Offset         uint64    `json:"offset"`
+	Len            uint64    `json:"length"`
+	BitFieldOffset uint8     `json:"bf-offset,omitempty"`
+	BitFieldLen    uint8     `json:"bf-len,omitempty"`
+	Kind           Kind      `json:"kind,omitempty"`
+	Template       *Template `json:"template,omitempty"`
+	Discriminator  string    `json:"discriminator,omitempty"`
+	Cases          []*Case   `json:"cases,omitempty"`
+	LenField       string    `json:"len-field,omitempty"`
+	CountField     string    `json:"count-field,omitempty"`
+	Elem           *Template `json:"elem,omitempty"`
+	PresentIf      string    `json:"present-if,omitempty"`
+	Prefix         uint8     `json:"prefix,omitempty"`
+	Padding        byte      `json:"padding,omitempty"`
+	Truncate       bool      `json:"truncate,omitempty"`
+	Charset        string    `json:"charset,omitempty"`
+	Digits         uint8     `json:"digits,omitempty"`
+	Scale          uint8     `json:"scale,omitempty"`
+	Signed         bool      `json:"signed,omitempty"`
+	Base           uint8     `json:"base,omitempty"`
+	Terminator     string    `json:"terminator,omitempty"`
+	Enum           *Enum     `json:"enum,omitempty"`
+	Flags          *FlagSet  `json:"flags,omitempty"`
+	Strict         bool      `json:"strict,omitempty"`
+	BigEndian      bool      `json:"big-endian,omitempty"`
 }
 
-func newField(t reflect.Type, name string, offset uint64) *Field {
+func newField(t reflect.Type, kind Kind, name string, offset uint64) *Field {
 	return &Field{
 		Name:   name,
 		Offset: offset,
 		Len:    uint64(t.Size()),
+		Kind:   kind,
 	}
 }
 
@@ -93,6 +234,156 @@ func (f *Field) updateSlice(data []byte, valuable Valuable) {
 	}
 }
 
+// uintOf returns the value of the integer field from data without allocating a
+// new Value.
+func (f *Field) uintOf(data []byte) uint64 {
+	if f.BitFieldLen != 0 {
+		return uint64(bitFieldOfByte(data[f.Offset],
+			f.BitFieldOffset,
+			f.BitFieldLen))
+	}
+	if f.BigEndian {
+		return uintOfBytesBE(f.slice(data))
+	}
+	return uintOfBytes(f.slice(data))
+}
+
+// putUint changes the integer field in data to v without allocating a new
+// Value.
+func (f *Field) putUint(data []byte, v uint64) {
+	if f.BitFieldLen != 0 {
+		setBitFieldOfByte(
+			&data[f.Offset],
+			f.BitFieldOffset,
+			f.BitFieldLen,
+			byte(v))
+		return
+	}
+	if f.BigEndian {
+		putUintBytesBE(f.slice(data), v)
+		return
+	}
+	putUintBytes(f.slice(data), v)
+}
+
+// uintValue returns v as the Value of the integer or floating point field, i.e.
+// a single byte for bit fields and Len bytes in the byte order of the field
+// otherwise.
+func (f *Field) uintValue(v uint64) Value {
+	if f.BitFieldLen != 0 {
+		return Value{byte(v)}
+	}
+	value := make(Value, f.Len)
+	if f.BigEndian {
+		putUintBytesBE(value, v)
+	} else {
+		putUintBytes(value, v)
+	}
+	return value
+}
+
+// fits returns true if v fits into the unsigned integer field.
+func fits(field *Field, v uint64) bool {
+	bits := field.bits()
+	return bits >= 64 || v < uint64(1)<<bits
+}
+
+// checkFits panics if v does not fit into the unsigned integer field.
+func checkFits(field *Field, v uint64) {
+	if !fits(field, v) {
+		panic(fmt.Sprintf("%d does not fit into field %s", v, field.Name))
+	}
+}
+
+// intOf returns the value of the integer field from data sign-extended to
+// int64.
+func (f *Field) intOf(data []byte) int64 {
+	shift := uint(64 - 8*f.Len)
+	return int64(f.uintOf(data)<<shift) >> shift
+}
+
+// floatOf returns the value of the floating point field from data.
+func (f *Field) floatOf(data []byte) float64 {
+	if f.Kind == Float32Kind {
+		return float64(math.Float32frombits(uint32(f.uintOf(data))))
+	}
+	return math.Float64frombits(f.uintOf(data))
+}
+
+// isInteger returns true if the field is an integer or a bit field.
+func (f *Field) isInteger() bool {
+	return f.BitFieldLen != 0 || f.Kind.Integer()
+}
+
+// int64Of returns the value of the integer field from data as int64. Signed
+// fields are sign-extended.
+func (f *Field) int64Of(data []byte) int64 {
+	if f.BitFieldLen == 0 && f.Kind.Signed() {
+		return f.intOf(data)
+	}
+	return int64(f.uintOf(data))
+}
+
+// compare compares the field in the data a and b. The result is 0 if the
+// fields are equal, -1 if the field in a is less than in b and +1 otherwise.
+// Numbers are compared numerically, other fields lexicographically.
+func (f *Field) compare(a, b []byte) int {
+	switch {
+	case f.Kind == Float32Kind || f.Kind == Float64Kind:
+		x, y := f.floatOf(a), f.floatOf(b)
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+		return 0
+	case f.BitFieldLen != 0 || (f.Kind.Integer() && !f.Kind.Signed()):
+		x, y := f.uintOf(a), f.uintOf(b)
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+		return 0
+	case f.Kind.Signed():
+		x, y := f.intOf(a), f.intOf(b)
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+		return 0
+	case f.Kind == Uint128Kind || f.Kind == BigUintKind || f.Kind == BigIntKind:
+		return f.bigOf(a).Cmp(f.bigOf(b))
+	case f.Kind == NumericTextKind:
+		x, errX := f.numberOf(a)
+		y, errY := f.numberOf(b)
+		if errX != nil || errY != nil {
+			return bytes.Compare(f.slice(a), f.slice(b))
+		}
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+		return 0
+	case f.Kind == ZonedDecimalKind || f.Kind == PackedDecimalKind:
+		x, errX := f.decimalOf(a)
+		y, errY := f.decimalOf(b)
+		if errX != nil || errY != nil {
+			return bytes.Compare(f.slice(a), f.slice(b))
+		}
+		if x.Unscaled < y.Unscaled {
+			return -1
+		} else if x.Unscaled > y.Unscaled {
+			return 1
+		}
+		return 0
+	default:
+		return bytes.Compare(f.slice(a), f.slice(b))
+	}
+}
+
 //BitField function creates a new Field with the given name, offset,
 //bitFieldOffset and bitFieldLen.
 func BitField(name string, offset uint64,
@@ -103,7 +394,7 @@ func BitField(name string, offset uint64,
 	if bitFieldOffset+bitFieldLen > 8 {
 		panic("invalid bitfield: offset+length cannot be larger than 8")
 	}
-	f := newField(reflect.TypeOf(uint8(0)), name, offset)
+	f := newField(reflect.TypeOf(uint8(0)), Uint8Kind, name, offset)
 	f.BitFieldOffset = bitFieldOffset
 	f.BitFieldLen = bitFieldLen
 	return f
@@ -113,6 +404,7 @@ func BitField(name string, offset uint64,
 //calculated to fit a uint8 value.
 func Uint8Field(name string, offset uint64) *Field {
 	return newField(reflect.TypeOf(uint8(0)),
+		Uint8Kind,
 		name,
 		offset,
 	)
@@ -122,6 +414,7 @@ func Uint8Field(name string, offset uint64) *Field {
 //calculated to fit an int8 value.
 func Int8Field(name string, offset uint64) *Field {
 	return newField(reflect.TypeOf(int8(0)),
+		Int8Kind,
 		name,
 		offset,
 	)
@@ -131,6 +424,7 @@ func Int8Field(name string, offset uint64) *Field {
 //calculated to fit a uint16 value.
 func Uint16Field(name string, offset uint64) *Field {
 	return newField(reflect.TypeOf(uint16(0)),
+		Uint16Kind,
 		name,
 		offset,
 	)
@@ -140,6 +434,7 @@ func Uint16Field(name string, offset uint64) *Field {
 //calculated to fit an int16 value.
 func Int16Field(name string, offset uint64) *Field {
 	return newField(reflect.TypeOf(int16(0)),
+		Int16Kind,
 		name,
 		offset,
 	)
@@ -149,6 +444,7 @@ func Int16Field(name string, offset uint64) *Field {
 //calculated to fit a uint32 value.
 func Uint32Field(name string, offset uint64) *Field {
 	return newField(reflect.TypeOf(uint32(0)),
+		Uint32Kind,
 		name,
 		offset,
 	)
@@ -159,6 +455,7 @@ func Uint32Field(name string, offset uint64) *Field {
 //calculated to fit an int32 value.
 func Int32Field(name string, offset uint64) *Field {
 	return newField(reflect.TypeOf(int32(0)),
+		Int32Kind,
 		name,
 		offset,
 	)
@@ -168,6 +465,7 @@ func Int32Field(name string, offset uint64) *Field {
 //calculated to fit a uint64 value.
 func Uint64Field(name string, offset uint64) *Field {
 	return newField(reflect.TypeOf(uint64(0)),
+		Uint64Kind,
 		name,
 		offset,
 	)
@@ -178,15 +476,54 @@ func Uint64Field(name string, offset uint64) *Field {
 //calculated to fit an int64 value.
 func Int64Field(name string, offset uint64) *Field {
 	return newField(reflect.TypeOf(int64(0)),
+		Int64Kind,
 		name,
 		offset,
 	)
 }
 
+//UintNField creates a new Field of Uint64Kind with the given name and offset
+//that stores an unsigned integer of the given number of bytes, e.g. 3 for a
+//24-bit integer. The Get method of Struct returns the value as uint64.
+//
+//The integer is stored in little-endian order unless the BigEndian option of
+//the Field is set, like for Uint128Field:
+//
+//  length := UintNField("length", 0, 3)
+//  length.BigEndian = true
+//
+//UintNField panics if bytes is not between 1 and 8.
+func UintNField(name string, offset uint64, bytes int) *Field {
+	checkIntLen(bytes)
+	return &Field{
+		Name:   name,
+		Offset: offset,
+		Len:    uint64(bytes),
+		Kind:   Uint64Kind,
+	}
+}
+
+//IntNField creates a new Field of Int64Kind with the given name and offset
+//that stores a signed integer of the given number of bytes, e.g. 3 for a
+//24-bit integer. The Get method of Struct returns the value sign-extended to
+//int64. The BigEndian option applies as for UintNField.
+//
+//IntNField panics if bytes is not between 1 and 8.
+func IntNField(name string, offset uint64, bytes int) *Field {
+	checkIntLen(bytes)
+	return &Field{
+		Name:   name,
+		Offset: offset,
+		Len:    uint64(bytes),
+		Kind:   Int64Kind,
+	}
+}
+
 //UintField creates a new Field with the given name and offset. Len is
 //calculated to fit a uint value.
 func UintField(name string, offset uint64) *Field {
 	return newField(reflect.TypeOf(uint(0)),
+		UintKind,
 		name,
 		offset,
 	)
@@ -196,6 +533,7 @@ func UintField(name string, offset uint64) *Field {
 //calculated to fit an int value.
 func IntField(name string, offset uint64) *Field {
 	return newField(reflect.TypeOf(int(0)),
+		IntKind,
 		name,
 		offset,
 	)
@@ -205,6 +543,27 @@ func IntField(name string, offset uint64) *Field {
 //calculated to fit an uintptr value.
 func UintptrField(name string, offset uint64) *Field {
 	return newField(reflect.TypeOf(uintptr(0)),
+		UintptrKind,
+		name,
+		offset,
+	)
+}
+
+//Float32Field creates a new Field with the given name and offset. Len is
+//calculated to fit a float32 value.
+func Float32Field(name string, offset uint64) *Field {
+	return newField(reflect.TypeOf(float32(0)),
+		Float32Kind,
+		name,
+		offset,
+	)
+}
+
+//Float64Field creates a new Field with the given name and offset. Len is
+//calculated to fit a float64 value.
+func Float64Field(name string, offset uint64) *Field {
+	return newField(reflect.TypeOf(float64(0)),
+		Float64Kind,
 		name,
 		offset,
 	)
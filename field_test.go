@@ -1,6 +1,9 @@
 package bmstruct
 
 import (
+	"encoding/json"
+	"errors"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -108,4 +111,77 @@ var _ = Describe("Field", func() {
 			})
 		})
 	})
+	Describe("Kind", func() {
+		It("should be set by the Field constructors", func() {
+			Expect(Uint16Field("f", 0).Kind).To(Equal(Uint16Kind))
+			Expect(IntField("f", 0).Kind).To(Equal(IntKind))
+			Expect(BitField("f", 0, 1, 2).Kind).To(Equal(Uint8Kind))
+			Expect((&Template{Size: 4}).Field("f", 0).Kind).To(Equal(BytesKind))
+		})
+		It("should be represented by its name in JSON", func() {
+			b, err := json.Marshal(Int32Field("f", 2))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(b)).To(Equal(`{"name":"f","offset":2,"length":4,"kind":"int32"}`))
+			var field Field
+			Expect(json.Unmarshal(b, &field)).To(Succeed())
+			Expect(field.Kind).To(Equal(Int32Kind))
+		})
+		It("should report an error for unknown names", func() {
+			var field Field
+			Expect(json.Unmarshal([]byte(`{"kind":"no-such-kind"}`), &field)).NotTo(Succeed())
+		})
+	})
+	Describe("odd-width integers", func() {
+		var s *Struct
+
+		BeforeEach(func() {
+			s = NewTemplate(-1,
+				UintNField("rgb", 0, 3),
+				IntNField("delta", 3, 3),
+				UintNField("mac", 6, 6),
+			).Empty()
+		})
+		It("should read and write the integers", func() {
+			Expect(s.Set("rgb", 0xff8000)).To(Succeed())
+			Expect(s.Lookup("rgb")).To(Equal(Value{0x00, 0x80, 0xff}))
+			Expect(s.Get("rgb")).To(Equal(uint64(0xff8000)))
+			Expect(s.Set("mac", uint64(0x0000_5e00_5301))).To(Succeed())
+			Expect(s.Lookup("mac").UintBytes()).To(Equal(uint64(0x5e005301)))
+		})
+		It("should sign-extend the signed integers", func() {
+			Expect(s.Set("delta", -2)).To(Succeed())
+			Expect(s.Lookup("delta")).To(Equal(Value{0xfe, 0xff, 0xff}))
+			Expect(s.Get("delta")).To(Equal(int64(-2)))
+			Expect(s.Set("delta", -0x800000)).To(Succeed())
+			Expect(s.Get("delta")).To(Equal(int64(-0x800000)))
+		})
+		It("should check the range", func() {
+			Expect(errors.Is(s.Set("rgb", 0x1000000), ErrOverflow)).To(BeTrue())
+			Expect(errors.Is(s.Set("delta", 0x800000), ErrOverflow)).To(BeTrue())
+			Expect(errors.Is(s.Set("delta", -0x800001), ErrOverflow)).To(BeTrue())
+		})
+		It("should respect the byte order", func() {
+			length := UintNField("length", 0, 3)
+			length.BigEndian = true
+			delta := IntNField("delta", 3, 3)
+			delta.BigEndian = true
+			ratio := Float32Field("ratio", 6)
+			ratio.BigEndian = true
+			t := NewTemplate(-1, length, delta, ratio)
+			s := t.Empty()
+			Expect(s.Set("length", 0x123456)).To(Succeed())
+			Expect(s.Set("delta", -2)).To(Succeed())
+			Expect(s.Set("ratio", 1.5)).To(Succeed())
+			Expect(s.Value).To(Equal(Value{0x12, 0x34, 0x56, 0xff, 0xff, 0xfe, 0x3f, 0xc0, 0, 0}))
+			Expect(s.Get("length")).To(Equal(uint64(0x123456)))
+			Expect(s.Get("delta")).To(Equal(int64(-2)))
+			Expect(s.Get("ratio")).To(Equal(float32(1.5)))
+			Expect(s.Lookup("length").UintBytesBE()).To(Equal(uint64(0x123456)))
+			Expect(func() { t.Accessor("length") }).To(Panic())
+		})
+		It("should panic for invalid lengths", func() {
+			Expect(func() { UintNField("f", 0, 0) }).To(Panic())
+			Expect(func() { IntNField("f", 0, 9) }).To(Panic())
+		})
+	})
 })
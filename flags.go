@@ -0,0 +1,267 @@
+package bmstruct
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//FlagSet names the bits of a flags Field, e.g. the bits of the TCP flags from
+//"FIN" (bit 0) to "CWR" (bit 7). The JSON representation of a FlagSet is an
+//object of the bit numbers keyed by the names.
+type FlagSet struct {
+	bits  map[string]uint8
+	names map[uint8]string
+}
+
+//NewFlagSet function creates a new FlagSet from the bit numbers keyed by the
+//names of the bits. Bit 0 is the least significant bit of the field.
+//
+//NewFlagSet panics if two names have the same bit, a bit number is larger than
+//63 or a name is empty, a number or contains '|'.
+func NewFlagSet(bits map[string]uint8) *FlagSet {
+	fs, err := newFlagSet(bits)
+	if err != nil {
+		panic(err.Error())
+	}
+	return fs
+}
+
+// newFlagSet creates a new FlagSet like NewFlagSet, but it returns an error
+// instead of panicking.
+func newFlagSet(bits map[string]uint8) (*FlagSet, error) {
+	fs := &FlagSet{
+		bits:  make(map[string]uint8, len(bits)),
+		names: make(map[uint8]string, len(bits)),
+	}
+	for name, bit := range bits {
+		if other, found := fs.names[bit]; found {
+			return nil, fmt.Errorf("flags %s and %s have the same bit %d", other, name, bit)
+		}
+		if _, err := strconv.ParseUint(name, 0, 64); err == nil || name == "" ||
+			strings.ContainsRune(name, '|') {
+			return nil, fmt.Errorf("invalid flag name %q", name)
+		}
+		if bit > 63 {
+			return nil, fmt.Errorf("invalid bit %d of flag %s", bit, name)
+		}
+		fs.bits[name] = bit
+		fs.names[bit] = name
+	}
+	return fs, nil
+}
+
+//Mask method returns the mask of the named bit and true or 0 and false if the
+//name is unknown.
+func (fs *FlagSet) Mask(name string) (uint64, bool) {
+	bit, found := fs.bits[name]
+	if !found {
+		return 0, false
+	}
+	return uint64(1) << bit, true
+}
+
+//Format method returns the names of the bits set in v joined by '|' in the
+//order of the bits, e.g. "SYN|ACK". The bits without a name are appended as a
+//hexadecimal number. Format returns "0" if no bits are set.
+func (fs *FlagSet) Format(v uint64) string {
+	if v == 0 {
+		return "0"
+	}
+	var names []string
+	for bit := uint8(0); bit < 64; bit++ {
+		if name, found := fs.names[bit]; found && v&(uint64(1)<<bit) != 0 {
+			names = append(names, name)
+			v &^= uint64(1) << bit
+		}
+	}
+	if v != 0 {
+		names = append(names, "0x"+strconv.FormatUint(v, 16))
+	}
+	return strings.Join(names, "|")
+}
+
+//Parse method parses the names of bits joined by '|', as returned by Format.
+//Besides the names, numbers in the syntax of Go integer literals are accepted
+//too. An empty string is 0. Parse returns an error wrapping ErrUnknownName for
+//an unknown name.
+func (fs *FlagSet) Parse(s string) (uint64, error) {
+	v := uint64(0)
+	if strings.TrimSpace(s) == "" {
+		return 0, nil
+	}
+	for _, name := range strings.Split(s, "|") {
+		name = strings.TrimSpace(name)
+		if mask, found := fs.Mask(name); found {
+			v |= mask
+		} else if n, err := strconv.ParseUint(name, 0, 64); err == nil {
+			v |= n
+		} else {
+			return 0, fmt.Errorf("flag %q: %w", name, ErrUnknownName)
+		}
+	}
+	return v, nil
+}
+
+//MarshalJSON implements the json.Marshaler interface.
+func (fs *FlagSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fs.bits)
+}
+
+//UnmarshalJSON implements the json.Unmarshaler interface.
+func (fs *FlagSet) UnmarshalJSON(b []byte) error {
+	var bits map[string]uint8
+	if err := json.Unmarshal(b, &bits); err != nil {
+		return err
+	}
+	parsed, err := newFlagSet(bits)
+	if err != nil {
+		return err
+	}
+	*fs = *parsed
+	return nil
+}
+
+//FlagsField function creates a new unsigned integer Field of the given size in
+//bytes (1, 2, 4 or 8) whose bits are named by the FlagSet, see Struct.Flags.
+//
+//The Set method of Struct accepts the names of the bits joined by '|' for a
+//flags Field too, e.g. s.Set("flags", "SYN|ACK").
+//
+//FlagsField panics for an invalid size or if a bit of the FlagSet does not fit
+//into the field.
+func FlagsField(name string, offset, size uint64, flags *FlagSet) *Field {
+	kinds := map[uint64]Kind{1: Uint8Kind, 2: Uint16Kind, 4: Uint32Kind, 8: Uint64Kind}
+	kind, found := kinds[size]
+	if !found {
+		panic(fmt.Sprintf("invalid size %d of flags field %s", size, name))
+	}
+	for flag, bit := range flags.bits {
+		if uint64(bit) >= 8*size {
+			panic(fmt.Sprintf("bit %d of flag %s does not fit into %d bytes", bit, flag, size))
+		}
+	}
+	return &Field{
+		Name:   name,
+		Offset: offset,
+		Len:    size,
+		Kind:   kind,
+		Flags:  flags,
+	}
+}
+
+//Flags is the value of a flags field of a Struct, see Struct.Flags. The
+//methods of Flags read and modify the field in the data of the Struct.
+type Flags struct {
+	set   *FlagSet
+	field func() *Field
+	s     *Struct
+}
+
+//Flags method returns the Flags of the flags field indicated by fieldName.
+//
+//Flags panics for a non-existing or absent field name or for a field that is
+//not a flags field.
+func (s *Struct) Flags(fieldName string) Flags {
+	field := s.resolvePresent(fieldName)
+	switch {
+	case field == nil:
+		panic(fmt.Sprintf("field %s is absent", fieldName))
+	case field.Flags == nil:
+		panic(fmt.Sprintf("field %s is not a flags field", fieldName))
+	}
+	return Flags{
+		set:   field.Flags,
+		field: s.fieldFunc(fieldName),
+		s:     s,
+	}
+}
+
+// mask returns the mask of the named bits. It panics for an unknown name.
+func (f Flags) mask(names []string) uint64 {
+	m := uint64(0)
+	for _, name := range names {
+		bit, found := f.set.Mask(name)
+		if !found {
+			panic(fmt.Sprintf("unknown flag %s", name))
+		}
+		m |= bit
+	}
+	return m
+}
+
+//Value method returns the value of the field.
+func (f Flags) Value() uint64 {
+	return f.field().uintOf(f.s.Value)
+}
+
+//Has method returns true if all of the named bits are set. It panics for an
+//unknown name.
+func (f Flags) Has(names ...string) bool {
+	m := f.mask(names)
+	return f.Value()&m == m
+}
+
+//Set method sets the named bits. It panics for an unknown name.
+func (f Flags) Set(names ...string) {
+	f.field().putUint(f.s.Value, f.Value()|f.mask(names))
+}
+
+//Clear method clears the named bits. It panics for an unknown name.
+func (f Flags) Clear(names ...string) {
+	f.field().putUint(f.s.Value, f.Value()&^f.mask(names))
+}
+
+//Names method returns the names of the set bits in the order of the bits.
+//Bits without a name are omitted.
+func (f Flags) Names() []string {
+	v := f.Value()
+	var names []string
+	for name, bit := range f.set.bits {
+		if v&(uint64(1)<<bit) != 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return f.set.bits[names[i]] < f.set.bits[names[j]]
+	})
+	return names
+}
+
+//String method returns the field formatted by FlagSet.Format, e.g. "SYN|ACK".
+func (f Flags) String() string {
+	return f.set.Format(f.Value())
+}
+
+//MarshalText implements the encoding.TextMarshaler interface, so Flags are
+//represented as a string like "SYN|ACK" in JSON.
+func (f Flags) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+//UnmarshalText implements the encoding.TextUnmarshaler interface. It parses
+//the text by FlagSet.Parse and stores the result into the field.
+func (f Flags) UnmarshalText(text []byte) error {
+	field := f.field()
+	value, err := field.encodeFlags(string(text))
+	if err != nil {
+		return err
+	}
+	copy(field.slice(f.s.Value), value)
+	return nil
+}
+
+// encodeFlags returns the Value of the flags field for the names of the bits
+// joined by '|'.
+func (f *Field) encodeFlags(names string) (Value, error) {
+	v, err := f.Flags.Parse(names)
+	if err != nil {
+		return nil, err
+	}
+	if !fits(f, v) {
+		return nil, fmt.Errorf("%s: %w", names, ErrOverflow)
+	}
+	return f.uintValue(v), nil
+}
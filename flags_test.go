@@ -0,0 +1,104 @@
+package bmstruct
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Flags fields", func() {
+	var tmpl *Template
+	var s *Struct
+
+	BeforeEach(func() {
+		tcp := NewFlagSet(map[string]uint8{
+			"FIN": 0, "SYN": 1, "RST": 2, "PSH": 3, "ACK": 4, "URG": 5,
+		})
+		tmpl = NewTemplate(-1,
+			Uint8Field("offset", 0),
+			FlagsField("tcpflags", 1, 1, tcp),
+			FlagsField("wide", 2, 2, NewFlagSet(map[string]uint8{"LOW": 0, "HIGH": 15})),
+		)
+		s = tmpl.Empty()
+	})
+	It("should set, clear and test the bits", func() {
+		flags := s.Flags("tcpflags")
+		Expect(flags.Has("SYN")).To(BeFalse())
+		flags.Set("SYN", "ACK")
+		Expect(s.Lookup("tcpflags")).To(Equal(Value{0x12}))
+		Expect(flags.Has("SYN")).To(BeTrue())
+		Expect(flags.Has("SYN", "ACK")).To(BeTrue())
+		Expect(flags.Has("SYN", "FIN")).To(BeFalse())
+		flags.Clear("SYN")
+		Expect(s.Flags("tcpflags").Value()).To(Equal(uint64(0x10)))
+		Expect(flags.Names()).To(Equal([]string{"ACK"}))
+		s.Flags("wide").Set("HIGH")
+		Expect(s.Lookup("wide")).To(Equal(Value{0x00, 0x80}))
+		Expect(func() { flags.Set("ECE") }).To(Panic())
+		Expect(func() { s.Flags("offset") }).To(Panic())
+	})
+	It("should return the masks of the bits", func() {
+		fs := tmpl.Fields["tcpflags"].Flags
+		mask, found := fs.Mask("ACK")
+		Expect(found).To(BeTrue())
+		Expect(mask).To(Equal(uint64(0x10)))
+		mask, found = fs.Mask("FIN")
+		Expect(found).To(BeTrue())
+		Expect(mask).To(Equal(uint64(1)))
+		mask, found = fs.Mask("ECE")
+		Expect(found).To(BeFalse())
+		Expect(mask).To(BeZero())
+	})
+	It("should be rendered and parsed as text", func() {
+		Expect(s.Flags("tcpflags").String()).To(Equal("0"))
+		Expect(s.Set("tcpflags", "SYN|ACK")).To(Succeed())
+		Expect(s.Get("tcpflags")).To(Equal(uint8(0x12)))
+		Expect(s.Flags("tcpflags").String()).To(Equal("SYN|ACK"))
+		s.Update("tcpflags", Value{0xc1})
+		Expect(s.Flags("tcpflags").String()).To(Equal("FIN|0xc0"))
+		Expect(s.Set("tcpflags", "FIN | 0x40")).To(Succeed())
+		Expect(s.Get("tcpflags")).To(Equal(uint8(0x41)))
+		Expect(errors.Is(s.Set("tcpflags", "SYN|ECE"), ErrUnknownName)).To(BeTrue())
+		Expect(errors.Is(s.Set("tcpflags", "0x100"), ErrOverflow)).To(BeTrue())
+	})
+	It("should be represented as a string in JSON", func() {
+		s.Flags("tcpflags").Set("RST", "ACK")
+		b, err := json.Marshal(map[string]Flags{"tcpflags": s.Flags("tcpflags")})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`{"tcpflags":"RST|ACK"}`))
+		flags := s.Flags("tcpflags")
+		Expect(json.Unmarshal([]byte(`"PSH|URG"`), &flags)).To(Succeed())
+		Expect(s.Get("tcpflags")).To(Equal(uint8(0x28)))
+		Expect(json.Unmarshal([]byte(`"NOPE"`), &flags)).NotTo(Succeed())
+	})
+	It("should be represented by names in the JSON of the Struct", func() {
+		s.Flags("tcpflags").Set("SYN", "ACK")
+		b, err := json.Marshal(s)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(ContainSubstring(`"fields":{"offset":0,"tcpflags":"SYN|ACK","wide":"0"}`))
+		b = []byte(strings.Replace(string(b), `"wide":"0"`, `"wide":"LOW|HIGH"`, 1))
+		var decoded Struct
+		Expect(json.Unmarshal(b, &decoded)).To(Succeed())
+		Expect(decoded.Flags("tcpflags").Names()).To(Equal([]string{"SYN", "ACK"}))
+		Expect(decoded.Get("wide")).To(Equal(uint16(0x8001)))
+	})
+	It("should panic for invalid definitions", func() {
+		Expect(func() { NewFlagSet(map[string]uint8{"A": 1, "B": 1}) }).To(Panic())
+		Expect(func() { NewFlagSet(map[string]uint8{"A|B": 1}) }).To(Panic())
+		Expect(func() { NewFlagSet(map[string]uint8{"A": 64}) }).To(Panic())
+		Expect(func() { FlagsField("f", 0, 3, NewFlagSet(nil)) }).To(Panic())
+		Expect(func() { FlagsField("f", 0, 1, NewFlagSet(map[string]uint8{"A": 8})) }).To(Panic())
+	})
+	It("should reject invalid definitions in JSON", func() {
+		var fs FlagSet
+		Expect(json.Unmarshal([]byte(`{"A":1,"B":2}`), &fs)).To(Succeed())
+		Expect(json.Unmarshal([]byte(`{"A|B":1}`), &fs)).To(MatchError(`invalid flag name "A|B"`))
+		Expect(json.Unmarshal([]byte(`{"A":64}`), &fs)).To(MatchError("invalid bit 64 of flag A"))
+		mask, found := fs.Mask("B")
+		Expect(found).To(BeTrue())
+		Expect(mask).To(Equal(uint64(4)))
+	})
+})
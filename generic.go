@@ -0,0 +1,104 @@
+package bmstruct
+
+import (
+	"fmt"
+	"math"
+	"unsafe"
+)
+
+//Number is the set of Go types that can be used with TypedField.
+type Number interface {
+	int8 | int16 | int32 | int64 | int |
+		uint8 | uint16 | uint32 | uint64 | uint | uintptr |
+		float32 | float64
+}
+
+// kindOf returns the Kind that corresponds to the type T.
+func kindOf[T Number]() Kind {
+	var zero T
+	switch any(zero).(type) {
+	case uint8:
+		return Uint8Kind
+	case int8:
+		return Int8Kind
+	case uint16:
+		return Uint16Kind
+	case int16:
+		return Int16Kind
+	case uint32:
+		return Uint32Kind
+	case int32:
+		return Int32Kind
+	case uint64:
+		return Uint64Kind
+	case int64:
+		return Int64Kind
+	case uint:
+		return UintKind
+	case int:
+		return IntKind
+	case uintptr:
+		return UintptrKind
+	case float32:
+		return Float32Kind
+	default:
+		return Float64Kind
+	}
+}
+
+//TypedField is a handle of a Field whose Go type is checked once, when the
+//TypedField is created by FieldOf. Like Accessor, a TypedField neither looks
+//up the field name nor allocates memory when it is used.
+type TypedField[T Number] struct {
+	field Field
+}
+
+//FieldOf function returns a TypedField for the field of the Template indicated
+//by name. The Kind and the length of the field shall match the type T, e.g. a
+//Uint16Field can be accessed as uint16 only. Bit fields can be accessed as
+//uint8.
+//
+//FieldOf panics for a non-existing field name, for a field whose position
+//depends on a preceding dynamic field or when the field does not match the type
+//T.
+func FieldOf[T Number](t *Template, name string) *TypedField[T] {
+	field := t.fixedField(name)
+	var zero T
+	kind := kindOf[T]()
+	if field.Kind != kind || (field.BitFieldLen == 0 && field.Len != uint64(unsafe.Sizeof(zero))) {
+		panic(fmt.Sprintf("field %s of kind %s (%d bytes) cannot be accessed as %T",
+			name, field.Kind, field.Len, zero))
+	}
+	return &TypedField[T]{
+		field: *field,
+	}
+}
+
+//Name method returns the name of the field.
+func (f *TypedField[T]) Name() string {
+	return f.field.Name
+}
+
+//Get method returns the field of s.
+func (f *TypedField[T]) Get(s *Struct) T {
+	switch {
+	case f.field.Kind == Float32Kind || f.field.Kind == Float64Kind:
+		return T(f.field.floatOf(s.Value))
+	case f.field.BitFieldLen == 0 && f.field.Kind.Signed():
+		return T(f.field.intOf(s.Value))
+	default:
+		return T(f.field.uintOf(s.Value))
+	}
+}
+
+//Set method changes the field of s to v.
+func (f *TypedField[T]) Set(s *Struct, v T) {
+	switch f.field.Kind {
+	case Float32Kind:
+		f.field.putUint(s.Value, uint64(math.Float32bits(float32(v))))
+	case Float64Kind:
+		f.field.putUint(s.Value, math.Float64bits(float64(v)))
+	default:
+		f.field.putUint(s.Value, uint64(v))
+	}
+}
@@ -0,0 +1,71 @@
+package bmstruct
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TypedField", func() {
+	var tmpl *Template
+	var s *Struct
+
+	BeforeEach(func() {
+		tmpl = NewTemplate(24,
+			Uint16Field("u16", 0),
+			Int16Field("i16", 2),
+			Float32Field("f32", 4),
+			Float64Field("f64", 8),
+			BitField("bf", 16, 4, 4),
+			IntField("int", 16),
+		)
+		s = tmpl.Empty()
+	})
+	It("should get and set the fields", func() {
+		u16 := FieldOf[uint16](tmpl, "u16")
+		i16 := FieldOf[int16](tmpl, "i16")
+		f32 := FieldOf[float32](tmpl, "f32")
+		f64 := FieldOf[float64](tmpl, "f64")
+		bf := FieldOf[uint8](tmpl, "bf")
+		u16.Set(s, 0x0102)
+		i16.Set(s, -2)
+		f32.Set(s, 1.5)
+		f64.Set(s, -0.25)
+		bf.Set(s, 9)
+		Expect(u16.Get(s)).To(Equal(uint16(0x0102)))
+		Expect(i16.Get(s)).To(Equal(int16(-2)))
+		Expect(f32.Get(s)).To(Equal(float32(1.5)))
+		Expect(f64.Get(s)).To(Equal(-0.25))
+		Expect(bf.Get(s)).To(Equal(uint8(9)))
+		Expect(bf.Name()).To(Equal("bf"))
+		Expect(s.Lookup("u16").Uint16()).To(Equal(uint16(0x0102)))
+		Expect(s.Lookup("f32").Float32()).To(Equal(float32(1.5)))
+		Expect(s.Lookup("int").Int64()).To(Equal(int64(0x90)))
+	})
+	It("should panic at construction for mismatching types", func() {
+		Expect(func() {
+			FieldOf[uint16](tmpl, "f32")
+		}).To(Panic())
+		Expect(func() {
+			FieldOf[int16](tmpl, "u16")
+		}).To(Panic())
+		Expect(func() {
+			FieldOf[float64](tmpl, "f32")
+		}).To(Panic())
+		Expect(func() {
+			FieldOf[uint16](tmpl, "bf")
+		}).To(Panic())
+		Expect(func() {
+			FieldOf[uint16](tmpl, "no-such-field")
+		}).To(Panic())
+	})
+	It("should not allocate", func() {
+		f64 := FieldOf[float64](tmpl, "f64")
+		i16 := FieldOf[int16](tmpl, "i16")
+		Expect(testing.AllocsPerRun(100, func() {
+			f64.Set(s, f64.Get(s)+1)
+			i16.Set(s, i16.Get(s)-1)
+		})).To(BeZero())
+	})
+})
@@ -0,0 +1,140 @@
+package bmstruct
+
+import (
+	"fmt"
+	"os"
+)
+
+//MapMode specifies whether a memory mapped file can be modified.
+type MapMode int
+
+const (
+	//ReadOnly mode maps the file for reading only. Modifying the data of a
+	//read-only mapping results in a runtime fault.
+	ReadOnly MapMode = iota
+	//ReadWrite mode maps the file for reading and writing. The modifications
+	//go straight to the file.
+	ReadWrite
+)
+
+//MappedStructs is a Structs whose Value is a memory mapped file. Modifying the
+//Structs, e.g. with its Update method, modifies the file itself.
+//
+//MappedStructs shall be closed with the Close method when it is not used
+//anymore. The Value of the Structs shall not be used after Close.
+type MappedStructs struct {
+	*Structs
+	file *os.File
+	mode MapMode
+}
+
+//OpenMapped opens the file at path and maps it into the memory as Structs of
+//the given Template. The size of the file shall be a multiple of the Template
+//size.
+func OpenMapped(path string, t *Template, mode MapMode) (*MappedStructs, error) {
+	flag := os.O_RDONLY
+	if mode == ReadWrite {
+		flag = os.O_RDWR
+	}
+	file, err := os.OpenFile(path, flag, 0)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.Size()%int64(t.Size) != 0 {
+		file.Close()
+		return nil, fmt.Errorf("size of %s (%d bytes) does not align with the template size (%d bytes)",
+			path, info.Size(), t.Size)
+	}
+	value, err := mmap(file, int(info.Size()), mode)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &MappedStructs{
+		Structs: &Structs{
+			Template: t,
+			Value:    value,
+		},
+		file: file,
+		mode: mode,
+	}, nil
+}
+
+//Sync method flushes the modifications of the mapped memory to the file.
+func (m *MappedStructs) Sync() error {
+	if m.mode != ReadWrite {
+		return nil
+	}
+	return msync(m.Value)
+}
+
+//Close method unmaps the file from the memory and closes it. Modifications
+//are flushed to the file before.
+func (m *MappedStructs) Close() error {
+	err := m.Sync()
+	if uerr := munmap(m.Value); err == nil {
+		err = uerr
+	}
+	m.Value = nil
+	if cerr := m.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+//Grow method appends n zeroed Struct objects to the end of the file and maps
+//the file again. The Value of the Structs changes, slices of the previous
+//Value shall not be used after Grow.
+//
+//Grow returns an error for read-only mappings and for a negative n or one
+//that would make the file larger than the largest int. If the file cannot be
+//extended or mapped again, Grow returns the error and the Structs keep the
+//previous content of the file.
+func (m *MappedStructs) Grow(n int) error {
+	if m.mode != ReadWrite {
+		return fmt.Errorf("read-only mapping cannot grow")
+	}
+	oldSize := len(m.Value)
+	if n < 0 || n > (maxInt-oldSize)/m.Template.Size {
+		return fmt.Errorf("cannot grow by %d structs", n)
+	}
+	size := oldSize + n*m.Template.Size
+	if err := msync(m.Value); err != nil {
+		return err
+	}
+	if err := munmap(m.Value); err != nil {
+		return err
+	}
+	m.Value = nil
+	if err := m.file.Truncate(int64(size)); err != nil {
+		return m.restore(oldSize, err)
+	}
+	value, err := mmap(m.file, size, m.mode)
+	if err != nil {
+		return m.restore(oldSize, err)
+	}
+	m.Value = value
+	m.rebuildIndexes()
+	return nil
+}
+
+// restore maps the first size bytes of the file again after a failed Grow, so
+// the Structs remain usable. It returns err, or err together with the error of
+// restoring the mapping.
+func (m *MappedStructs) restore(size int, err error) error {
+	if terr := m.file.Truncate(int64(size)); terr != nil {
+		return fmt.Errorf("%w (restoring the file size: %s)", err, terr)
+	}
+	value, merr := mmap(m.file, size, m.mode)
+	if merr != nil {
+		return fmt.Errorf("%w (restoring the mapping: %s)", err, merr)
+	}
+	m.Value = value
+	m.rebuildIndexes()
+	return err
+}
@@ -0,0 +1,5 @@
+package bmstruct
+
+// sysMsync is the number of the msync system call. The syscall package does not
+// define it on netbsd, where msync is the __msync13 system call.
+const sysMsync = 277
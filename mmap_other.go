@@ -0,0 +1,20 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package bmstruct
+
+import (
+	"fmt"
+	"os"
+)
+
+func mmap(file *os.File, size int, mode MapMode) (Value, error) {
+	return nil, fmt.Errorf("memory mapped files are not supported on this platform")
+}
+
+func munmap(v Value) error {
+	return nil
+}
+
+func msync(v Value) error {
+	return nil
+}
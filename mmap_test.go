@@ -0,0 +1,101 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package bmstruct
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MappedStructs", func() {
+	var tmpl *Template
+	var dir, path string
+
+	BeforeEach(func() {
+		tmpl = NewTemplate(4,
+			Uint16Field("id", 0),
+			Uint16Field("value", 2),
+		)
+		var err error
+		dir, err = ioutil.TempDir("", "bmstruct")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(dir, "data")
+		Expect(ioutil.WriteFile(path, []byte{1, 0, 2, 0, 3, 0, 4, 0}, 0644)).To(Succeed())
+	})
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+	Context("when opened for reading and writing", func() {
+		var m *MappedStructs
+
+		BeforeEach(func() {
+			var err error
+			m, err = OpenMapped(path, tmpl, ReadWrite)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("should map the file content", func() {
+			Expect(m.Count()).To(Equal(uint32(2)))
+			Expect(m.Column("value")).To(Equal([]uint16{2, 4}))
+			Expect(m.Close()).To(Succeed())
+		})
+		It("should write the updates to the file", func() {
+			m.Update(4, tmpl.New(Value{5, 0, 6, 0}))
+			Expect(m.Sync()).To(Succeed())
+			Expect(ioutil.ReadFile(path)).To(Equal([]byte{1, 0, 2, 0, 5, 0, 6, 0}))
+			Expect(m.Close()).To(Succeed())
+		})
+		It("should grow the file", func() {
+			Expect(m.Grow(1)).To(Succeed())
+			Expect(m.Count()).To(Equal(uint32(3)))
+			m.SetColumn("id", []uint16{7, 8, 9})
+			Expect(m.Close()).To(Succeed())
+			Expect(ioutil.ReadFile(path)).To(Equal([]byte{7, 0, 2, 0, 8, 0, 4, 0, 9, 0, 0, 0}))
+		})
+		It("should reject growing beyond the largest int", func() {
+			Expect(m.Grow(maxInt / tmpl.Size)).NotTo(Succeed())
+			Expect(m.Grow(-1)).NotTo(Succeed())
+			Expect(m.Count()).To(Equal(uint32(2)))
+			Expect(m.Column("value")).To(Equal([]uint16{2, 4}))
+			m.SetColumn("id", []uint16{7, 8})
+			Expect(m.Close()).To(Succeed())
+			Expect(ioutil.ReadFile(path)).To(Equal([]byte{7, 0, 2, 0, 8, 0, 4, 0}))
+		})
+	})
+	Context("when opened for reading only", func() {
+		It("should not grow", func() {
+			m, err := OpenMapped(path, tmpl, ReadOnly)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m.Column("id")).To(Equal([]uint16{1, 3}))
+			Expect(m.Grow(1)).NotTo(Succeed())
+			Expect(m.Close()).To(Succeed())
+		})
+	})
+	Context("when the file is empty", func() {
+		It("should be grown from zero", func() {
+			Expect(ioutil.WriteFile(path, []byte{}, 0644)).To(Succeed())
+			m, err := OpenMapped(path, tmpl, ReadWrite)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m.Count()).To(Equal(uint32(0)))
+			Expect(m.Grow(2)).To(Succeed())
+			Expect(m.Count()).To(Equal(uint32(2)))
+			Expect(m.Close()).To(Succeed())
+		})
+	})
+	Context("when the file size does not align", func() {
+		It("should return an error", func() {
+			Expect(ioutil.WriteFile(path, []byte{1, 2, 3}, 0644)).To(Succeed())
+			_, err := OpenMapped(path, tmpl, ReadOnly)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("when the file does not exist", func() {
+		It("should return an error", func() {
+			_, err := OpenMapped(filepath.Join(dir, "no-such-file"), tmpl, ReadOnly)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,43 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package bmstruct
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func mmap(file *os.File, size int, mode MapMode) (Value, error) {
+	if size == 0 {
+		return Value{}, nil
+	}
+	prot := syscall.PROT_READ
+	if mode == ReadWrite {
+		prot |= syscall.PROT_WRITE
+	}
+	b, err := syscall.Mmap(int(file.Fd()), 0, size, prot, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func munmap(v Value) error {
+	if len(v) == 0 {
+		return nil
+	}
+	return syscall.Munmap(v)
+}
+
+func msync(v Value) error {
+	if len(v) == 0 {
+		return nil
+	}
+	_, _, errno := syscall.Syscall(sysMsync,
+		uintptr(unsafe.Pointer(&v[0])), uintptr(len(v)), syscall.MS_SYNC)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package bmstruct
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//NumericTextField function creates a new Field of NumericTextKind with the
+//given name, offset and length that stores an unsigned integer as ASCII digits
+//of the given base, e.g. the octal size of a tar header or the decimal size of
+//an ar header. The base shall be between 2 and 36.
+//
+//The Padding option of the Field is '0' by default, which means the number is
+//right-aligned and filled with leading zeros. With any other padding the number
+//is left-aligned, followed by the padding. The Terminator option is written
+//right after the digits, e.g. a tar header field is
+//
+//  size := NumericTextField("size", 124, 12, 8)
+//  size.Terminator = "\x00"
+//
+//and an ar header field is
+//
+//  size := NumericTextField("size", 48, 10, 10)
+//  size.Padding = ' '
+//
+//On decoding, leading and trailing spaces and NULs are skipped, so a field
+//containing no digits at all is 0.
+//
+//The Get method of Struct returns an uint64, or the Value of the field if it
+//does not contain a valid number, e.g. a tar size field in the base-256
+//encoding of GNU tar. The Set method accepts any Go integer type and returns
+//an error wrapping ErrOverflow if the number is negative or its digits and the
+//terminator do not fit into the field.
+//
+//NumericTextField panics for an invalid base.
+func NumericTextField(name string, offset, length uint64, base uint8) *Field {
+	if base < 2 || base > 36 {
+		panic(fmt.Sprintf("invalid base %d", base))
+	}
+	return &Field{
+		Name:    name,
+		Offset:  offset,
+		Len:     length,
+		Kind:    NumericTextKind,
+		Base:    base,
+		Padding: '0',
+	}
+}
+
+// numberOf returns the number stored in the numeric text field. It returns an
+// error if the digits are followed by anything but spaces, NULs, the padding
+// or the terminator.
+func (f *Field) numberOf(data []byte) (uint64, error) {
+	b := f.slice(data)
+	text := strings.TrimLeft(string(b), " \x00")
+	i := 0
+	for i < len(text) && digitOf(text[i]) < int(f.Base) {
+		i++
+	}
+	if strings.Trim(text[i:], " \x00"+f.Terminator+string(rune(f.Padding))) != "" {
+		return 0, fmt.Errorf("invalid number %q", b)
+	}
+	if i == 0 {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(text[:i], int(f.Base), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q: %w", b, ErrOverflow)
+	}
+	return n, nil
+}
+
+// digitOf returns the value of the digit c in base 36 or 36 if c is not a
+// digit.
+func digitOf(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'a' && c <= 'z':
+		return int(c-'a') + 10
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 10
+	}
+	return 36
+}
+
+// encodeNumber returns the Value of the numeric text field.
+func (f *Field) encodeNumber(v interface{}) (Value, error) {
+	abs, negative, ok := integerOf(v)
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("value of type %T cannot be stored in a field of kind %s",
+			v, f.Kind)
+	case negative:
+		return nil, fmt.Errorf("-%d: %w", abs, ErrOverflow)
+	}
+	text := strconv.FormatUint(abs, int(f.Base)) + f.Terminator
+	if len(text) > int(f.Len) {
+		return nil, fmt.Errorf("%d: %w", abs, ErrOverflow)
+	}
+	value := make(Value, f.Len)
+	if f.Padding == '0' {
+		copy(value, strings.Repeat("0", int(f.Len)-len(text)))
+		copy(value[int(f.Len)-len(text):], text)
+		return value, nil
+	}
+	copy(value, text)
+	for i := len(text); i < len(value); i++ {
+		value[i] = f.Padding
+	}
+	return value, nil
+}
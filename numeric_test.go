@@ -0,0 +1,69 @@
+package bmstruct
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Numeric text fields", func() {
+	var tmpl *Template
+	var s *Struct
+
+	BeforeEach(func() {
+		mode := NumericTextField("mode", 0, 8, 8)
+		mode.Terminator = "\x00"
+		size := NumericTextField("size", 8, 12, 8)
+		size.Terminator = "\x00"
+		arSize := NumericTextField("ar-size", 20, 10, 10)
+		arSize.Padding = ' '
+		tmpl = NewTemplate(-1,
+			mode,
+			size,
+			arSize,
+			NumericTextField("hex", 30, 4, 16),
+		)
+		s = tmpl.Empty()
+	})
+	It("should format the numbers", func() {
+		Expect(s.Set("mode", 0644)).To(Succeed())
+		Expect(s.Lookup("mode")).To(Equal(Value("0000644\x00")))
+		Expect(s.Set("size", uint64(1234))).To(Succeed())
+		Expect(s.Lookup("size")).To(Equal(Value("00000002322\x00")))
+		Expect(s.Get("size")).To(Equal(uint64(1234)))
+		Expect(s.Set("ar-size", 1234)).To(Succeed())
+		Expect(s.Lookup("ar-size")).To(Equal(Value("1234      ")))
+		Expect(s.Get("ar-size")).To(Equal(uint64(1234)))
+		Expect(s.Set("hex", 0xbeef)).To(Succeed())
+		Expect(s.Lookup("hex")).To(Equal(Value("beef")))
+		Expect(s.Get("hex")).To(Equal(uint64(0xbeef)))
+	})
+	It("should parse the numbers leniently", func() {
+		Expect(s.Get("size")).To(Equal(uint64(0)))
+		s.Update("mode", Value("   644 \x00"))
+		Expect(s.Get("mode")).To(Equal(uint64(0644)))
+		s.Update("hex", Value("BEEF"))
+		Expect(s.Get("hex")).To(Equal(uint64(0xbeef)))
+	})
+	It("should report invalid numbers", func() {
+		s.Update("mode", Value("0000694\x00"))
+		_, err := s.Number("mode")
+		Expect(err).To(HaveOccurred())
+		Expect(s.Get("mode")).To(Equal(Value("0000694\x00")))
+		s.Update("mode", Value{0x80, 0, 0, 0, 0, 0, 0, 1})
+		Expect(s.Get("mode")).To(Equal(Value{0x80, 0, 0, 0, 0, 0, 0, 1}))
+		_, err = s.Number("ar-size")
+		Expect(err).NotTo(HaveOccurred())
+	})
+	It("should check the range", func() {
+		Expect(errors.Is(s.Set("mode", 010000000), ErrOverflow)).To(BeTrue())
+		Expect(s.Set("mode", 07777777)).To(Succeed())
+		Expect(errors.Is(s.Set("size", -1), ErrOverflow)).To(BeTrue())
+		Expect(s.Set("size", "1")).NotTo(Succeed())
+	})
+	It("should panic for an invalid base", func() {
+		Expect(func() { NumericTextField("n", 0, 4, 1) }).To(Panic())
+		Expect(func() { NumericTextField("n", 0, 4, 37) }).To(Panic())
+	})
+})
@@ -0,0 +1,81 @@
+package bmstruct
+
+import (
+	"errors"
+	"fmt"
+)
+
+//ErrFieldAbsent is returned (wrapped) by LookupE when the looked up optional
+//field is absent.
+var ErrFieldAbsent = errors.New("field absent")
+
+//OptionalField function turns the given field into an optional field that is
+//present only if the integer field indicated by presentIf, typically a single
+//bit wide bit field, is not zero. It returns a copy of the given field, the
+//given field is not modified.
+//
+//An optional field is a dynamic field, i.e. the Fields declared after it at the
+//same or a larger offset are declared as if it were absent and they are moved
+//by its length when it is present, e.g.
+//
+//  t := NewTemplate(-1,
+//      BitField("flags.ext", 0, 0, 1),
+//      OptionalField(Uint16Field("ext", 1), "flags.ext"),
+//      Uint8Field("payload", 1),
+//  )
+//
+//describes a record where "payload" is at offset 1 or 3 depending on the flag.
+//
+//Several optional fields may share a presence field. They are present or absent
+//together, so inserting or removing one of them inserts (filled with zeros) or
+//removes the others too.
+//
+//OptionalField panics if the given field is already dynamic.
+func OptionalField(field *Field, presentIf string) *Field {
+	if field.dynamic() {
+		panic(fmt.Sprintf("field %s is already dynamic", field.Name))
+	}
+	optional := *field
+	optional.PresentIf = presentIf
+	return &optional
+}
+
+//Present method returns true if the field indicated by fieldName is present.
+//Fields that are not optional are always present.
+//
+//Present panics for a non-existing field name.
+func (s *Struct) Present(fieldName string) bool {
+	return s.resolvePresent(fieldName) != nil
+}
+
+//LookupE method works like Lookup, but it returns an error instead of
+//panicking. The error wraps ErrFieldAbsent if the field is an absent optional
+//field.
+func (s *Struct) LookupE(fieldName string) (Value, error) {
+	r, err := s.Template.resolve(s.Value, fieldName)
+	if err != nil {
+		return nil, err
+	}
+	return r.field.copySlice(s.Value), nil
+}
+
+//Remove method removes the optional field indicated by fieldName from the
+//Struct and clears its presence field. The other optional fields sharing the
+//presence field are removed too. Removing an absent field does nothing. As
+//Update, Remove reallocates the data of the Struct.
+//
+//Remove panics for a non-existing field name or for a field that is not
+//optional.
+func (s *Struct) Remove(fieldName string) {
+	original := s.Template.lookupField(fieldName)
+	if original.PresentIf == "" {
+		panic(fmt.Sprintf("field %s is not optional", fieldName))
+	}
+	l := s.Template.layout(s.Value)
+	if _, present := l.fields[fieldName]; !present {
+		return
+	}
+	presence := l.fields[original.PresentIf]
+	presence.putUint(s.Value, 0)
+	s.resizeDependents(l, 0, s.Template.fieldOrder().dependents[presence.Name])
+}
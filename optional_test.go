@@ -0,0 +1,130 @@
+package bmstruct
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Optional fields", func() {
+	var tmpl *Template
+
+	BeforeEach(func() {
+		tmpl = NewTemplate(-1,
+			BitField("flags.ext", 0, 0, 1),
+			BitField("flags.opt", 0, 1, 1),
+			OptionalField(Uint16Field("ext", 1), "flags.ext"),
+			OptionalField(Uint8Field("opt", 1), "flags.opt"),
+			Uint8Field("payload", 1),
+		)
+	})
+	It("should not count absent fields in the minimum size", func() {
+		Expect(tmpl.Size).To(Equal(2))
+	})
+	It("should shift the following fields", func() {
+		s := tmpl.New(Value{0, 9})
+		Expect(s.Lookup("payload").Uint8()).To(Equal(uint8(9)))
+		s = tmpl.New(Value{1, 0x34, 0x12, 9})
+		Expect(s.Lookup("ext").Uint16()).To(Equal(uint16(0x1234)))
+		Expect(s.Lookup("payload").Uint8()).To(Equal(uint8(9)))
+		s = tmpl.New(Value{3, 0x34, 0x12, 7, 9})
+		Expect(s.Lookup("opt").Uint8()).To(Equal(uint8(7)))
+		Expect(s.Lookup("payload").Uint8()).To(Equal(uint8(9)))
+		s, n, err := tmpl.Parse(Value{2, 7, 9, 0xff})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(3))
+		Expect(s.Present("ext")).To(BeFalse())
+		Expect(s.Present("opt")).To(BeTrue())
+		Expect(s.Present("payload")).To(BeTrue())
+	})
+	It("should report absent fields", func() {
+		s := tmpl.New(Value{0, 9})
+		Expect(s.Lookup("ext")).To(BeNil())
+		Expect(s.LookupFunc("ext")()).To(BeNil())
+		_, err := s.LookupE("ext")
+		Expect(errors.Is(err, ErrFieldAbsent)).To(BeTrue())
+		_, err = s.LookupE("no-such-field")
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, ErrFieldAbsent)).To(BeFalse())
+		v, err := s.LookupE("payload")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v).To(Equal(Value{9}))
+		Expect(func() {
+			s.Lookup("no-such-field")
+		}).To(Panic())
+	})
+	It("should insert and remove fields when the presence field is updated", func() {
+		s := tmpl.New(Value{0, 9})
+		s.Update("flags.ext", Value{1})
+		Expect(s.Value).To(Equal(Value{1, 0, 0, 9}))
+		Expect(s.Set("flags.opt", 1)).To(Succeed())
+		Expect(s.Value).To(Equal(Value{3, 0, 0, 0, 9}))
+		s.Update("ext", Uint16(0x1234))
+		s.Update("flags.ext", Value{0})
+		Expect(s.Value).To(Equal(Value{2, 0, 9}))
+		Expect(s.Lookup("payload").Uint8()).To(Equal(uint8(9)))
+	})
+	It("should insert and remove fields", func() {
+		s := tmpl.New(Value{0, 9})
+		s.Update("opt", Uint8(7))
+		Expect(s.Value).To(Equal(Value{2, 7, 9}))
+		s.Update("ext", Uint16(0x1234))
+		Expect(s.Value).To(Equal(Value{3, 0x34, 0x12, 7, 9}))
+		s.Update("ext", Uint16(0x5678))
+		Expect(s.Value).To(Equal(Value{3, 0x78, 0x56, 7, 9}))
+		s.Remove("ext")
+		Expect(s.Value).To(Equal(Value{2, 7, 9}))
+		s.Remove("ext")
+		Expect(s.Value).To(Equal(Value{2, 7, 9}))
+		Expect(func() {
+			s.Remove("payload")
+		}).To(Panic())
+		Expect(func() {
+			s.Update("opt", Uint16(1))
+		}).To(Panic())
+	})
+	It("should insert and remove the fields sharing a presence field together", func() {
+		t := NewTemplate(-1,
+			BitField("ext", 0, 0, 1),
+			BitField("other", 0, 1, 1),
+			OptionalField(Uint8Field("a", 1), "ext"),
+			OptionalField(Uint8Field("b", 1), "ext"),
+			Uint8Field("tail", 1),
+		)
+		s := t.New(Value{3, 0xaa, 0xbb, 0xcc})
+		s.Remove("a")
+		Expect(s.Value).To(Equal(Value{2, 0xcc}))
+		Expect(s.Present("b")).To(BeFalse())
+		Expect(s.Lookup("tail").Uint8()).To(Equal(uint8(0xcc)))
+		s.Update("a", Uint8(0xaa))
+		Expect(s.Value).To(Equal(Value{3, 0xaa, 0, 0xcc}))
+		Expect(s.Lookup("b").Uint8()).To(BeZero())
+		Expect(s.Lookup("tail").Uint8()).To(Equal(uint8(0xcc)))
+		Expect(s.Set("b", uint8(0xbb))).To(Succeed())
+		Expect(s.Value).To(Equal(Value{3, 0xaa, 0xbb, 0xcc}))
+	})
+	It("should not modify the given field", func() {
+		id := Uint8Field("id", 1)
+		opt := OptionalField(id, "flags.ext")
+		Expect(opt.PresentIf).To(Equal("flags.ext"))
+		Expect(id.PresentIf).To(BeEmpty())
+		t := NewTemplate(-1, Uint8Field("flags", 0), id)
+		Expect(t.Size).To(Equal(2))
+	})
+	It("should panic for invalid definitions", func() {
+		Expect(func() {
+			NewTemplate(-1, OptionalField(Uint8Field("opt", 0), "flag"))
+		}).To(Panic())
+		Expect(func() {
+			OptionalField(DynamicField("body", 1, "len"), "flag")
+		}).To(Panic())
+		Expect(func() {
+			NewTemplate(-1,
+				Uint8Field("flag", 0),
+				OptionalField(Uint8Field("len", 1), "flag"),
+				DynamicField("body", 1, "len"),
+			)
+		}).To(Panic())
+	})
+})
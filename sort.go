@@ -0,0 +1,177 @@
+package bmstruct
+
+import (
+	"sort"
+)
+
+type structsByFields struct {
+	ss     *Structs
+	fields []*Field
+	tmp    []byte
+}
+
+func (s *structsByFields) Len() int {
+	return int(s.ss.Count())
+}
+
+func (s *structsByFields) Less(i, j int) bool {
+	a, b := s.ss.row(i), s.ss.row(j)
+	for _, field := range s.fields {
+		if c := field.compare(a, b); c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
+
+func (s *structsByFields) Swap(i, j int) {
+	a, b := s.ss.row(i), s.ss.row(j)
+	copy(s.tmp, a)
+	copy(a, b)
+	copy(b, s.tmp)
+}
+
+//SortBy method sorts the Struct objects of the Structs in place by the fields
+//indicated by fieldNames. The first field is the primary sort key, the
+//following fields are only used for breaking ties. Integer fields are ordered
+//numerically, other fields lexicographically by their bytes.
+//
+//The sort is stable, i.e. Struct objects with equal keys keep their original
+//order.
+//
+//SortBy panics for a non-existing field name or when no field name was given.
+func (ss *Structs) SortBy(fieldNames ...string) {
+	if len(fieldNames) == 0 {
+		panic("at least 1 field name shall be specified")
+	}
+	fields := make([]*Field, len(fieldNames))
+	for i, fieldName := range fieldNames {
+		fields[i] = ss.Template.lookupField(fieldName)
+	}
+	sort.Stable(&structsByFields{
+		ss:     ss,
+		fields: fields,
+		tmp:    make([]byte, ss.Template.Size),
+	})
+	ss.rebuildIndexes()
+}
+
+//Search method uses binary search to find the first Struct object in which the
+//field indicated by fieldName is not less than key. It returns the index of
+//that Struct object and whether its field equals to key. If there is no such
+//Struct object, the returned index is Count().
+//
+//The Structs shall be sorted by the field in ascending order, e.g. with the
+//SortBy method.
+//
+//Search panics for a non-existing field name or a key of incorrect size.
+func (ss *Structs) Search(fieldName string, key Valuable) (int, bool) {
+	field := ss.Template.lookupField(fieldName)
+	keyData := make([]byte, ss.Template.Size)
+	field.updateSlice(keyData, key)
+	n := int(ss.Count())
+	i := sort.Search(n, func(i int) bool {
+		return field.compare(ss.row(i), keyData) >= 0
+	})
+	return i, i < n && field.compare(ss.row(i), keyData) == 0
+}
+
+//Index is a hash index of a Structs that maps the values of a field to the
+//indices of the Struct objects holding that value.
+//
+//An Index created by the Index method of Structs is kept in sync with the
+//Structs as long as the Structs is modified through its methods.
+type Index struct {
+	field   *Field
+	entries map[string][]int
+}
+
+func newIndex(field *Field) *Index {
+	return &Index{
+		field:   field,
+		entries: make(map[string][]int),
+	}
+}
+
+func (idx *Index) add(data []byte, n int) {
+	key := string(idx.field.copySlice(data))
+	entry := idx.entries[key]
+	i := sort.SearchInts(entry, n)
+	entry = append(entry, 0)
+	copy(entry[i+1:], entry[i:])
+	entry[i] = n
+	idx.entries[key] = entry
+}
+
+func (idx *Index) remove(data []byte, n int) {
+	key := string(idx.field.copySlice(data))
+	entry := idx.entries[key]
+	i := sort.SearchInts(entry, n)
+	if i == len(entry) || entry[i] != n {
+		return
+	}
+	entry = append(entry[:i], entry[i+1:]...)
+	if len(entry) == 0 {
+		delete(idx.entries, key)
+	} else {
+		idx.entries[key] = entry
+	}
+}
+
+func (idx *Index) build(ss *Structs) {
+	idx.entries = make(map[string][]int)
+	for n := 0; n < int(ss.Count()); n++ {
+		idx.add(ss.row(n), n)
+	}
+}
+
+//FieldName method returns the name of the indexed field.
+func (idx *Index) FieldName() string {
+	return idx.field.Name
+}
+
+//Lookup method returns the indices of the Struct objects in which the indexed
+//field equals to key. The indices are returned in ascending order. Lookup
+//returns an empty slice if there is no such Struct object.
+func (idx *Index) Lookup(key Valuable) []int {
+	entry := idx.entries[string(key.GetValue())]
+	result := make([]int, len(entry))
+	copy(result, entry)
+	return result
+}
+
+//Index method returns a hash Index of the field indicated by fieldName. The
+//Index is kept up to date by the methods of the Structs changing the data
+//(Update, SetColumn and SortBy). Changing the Value of the Structs directly
+//invalidates the Index.
+//
+//Calling Index for the same field twice returns the same Index.
+//
+//Index panics for a non-existing field name.
+func (ss *Structs) Index(fieldName string) *Index {
+	field := ss.Template.lookupField(fieldName)
+	for _, idx := range ss.indexes {
+		if idx.field == field {
+			return idx
+		}
+	}
+	idx := newIndex(field)
+	idx.build(ss)
+	ss.indexes = append(ss.indexes, idx)
+	return idx
+}
+
+func (ss *Structs) rebuildIndexes() {
+	for _, idx := range ss.indexes {
+		idx.build(ss)
+	}
+}
+
+// updateIndexes updates the indexes of the Structs when the nth Struct object
+// changes from oldData to the current content.
+func (ss *Structs) updateIndexes(n int, oldData []byte) {
+	for _, idx := range ss.indexes {
+		idx.remove(oldData, n)
+		idx.add(ss.row(n), n)
+	}
+}
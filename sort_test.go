@@ -0,0 +1,115 @@
+package bmstruct
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sort", func() {
+	var tmpl *Template
+	var ss *Structs
+	var sliceData Value
+
+	BeforeEach(func() {
+		tmpl = NewTemplate(4,
+			Uint8Field("id", 0),
+			Int16Field("key", 1),
+			BitField("prio", 3, 0, 2),
+		)
+		sliceData = Value{
+			0, 3, 0, 1,
+			1, 0xff, 0xff, 0,
+			2, 3, 0, 0,
+			3, 1, 0, 1,
+		}
+		ss = tmpl.Slice(sliceData)
+	})
+	Describe("the SortBy method call", func() {
+		It("shall sort the data in place", func() {
+			ss.SortBy("key")
+			Expect(ss.Column("id")).To(Equal([]uint8{1, 3, 0, 2}))
+			Expect(sliceData).To(Equal(Value{
+				1, 0xff, 0xff, 0,
+				3, 1, 0, 1,
+				0, 3, 0, 1,
+				2, 3, 0, 0,
+			}))
+		})
+		It("shall use the following fields for breaking ties", func() {
+			ss.SortBy("key", "prio")
+			Expect(ss.Column("id")).To(Equal([]uint8{1, 3, 2, 0}))
+		})
+		It("shall be stable", func() {
+			ss.SortBy("prio")
+			Expect(ss.Column("id")).To(Equal([]uint8{1, 2, 0, 3}))
+		})
+		It("shall panic for non-existing field", func() {
+			Expect(func() {
+				ss.SortBy("no-such-field")
+			}).To(Panic())
+			Expect(func() {
+				ss.SortBy()
+			}).To(Panic())
+		})
+	})
+	Describe("the Search method call", func() {
+		BeforeEach(func() {
+			ss.SortBy("key")
+		})
+		It("shall find existing keys", func() {
+			i, found := ss.Search("key", Int16(-1))
+			Expect(i).To(Equal(0))
+			Expect(found).To(BeTrue())
+			i, found = ss.Search("key", Int16(3))
+			Expect(i).To(Equal(2))
+			Expect(found).To(BeTrue())
+		})
+		It("shall report the insertion point of missing keys", func() {
+			i, found := ss.Search("key", Int16(2))
+			Expect(i).To(Equal(2))
+			Expect(found).To(BeFalse())
+			i, found = ss.Search("key", Int16(42))
+			Expect(i).To(Equal(4))
+			Expect(found).To(BeFalse())
+		})
+		It("shall panic for a key of incorrect size", func() {
+			Expect(func() {
+				ss.Search("key", Uint32(3))
+			}).To(Panic())
+		})
+	})
+	Describe("the Index method call", func() {
+		var idx *Index
+
+		BeforeEach(func() {
+			idx = ss.Index("key")
+		})
+		It("shall return the same index for the same field", func() {
+			Expect(ss.Index("key")).To(BeIdenticalTo(idx))
+			Expect(idx.FieldName()).To(Equal("key"))
+		})
+		It("shall map the values to the indices", func() {
+			Expect(idx.Lookup(Int16(3))).To(Equal([]int{0, 2}))
+			Expect(idx.Lookup(Int16(-1))).To(Equal([]int{1}))
+			Expect(idx.Lookup(Int16(42))).To(BeEmpty())
+		})
+		It("shall be kept in sync by Update", func() {
+			ss.Update(4, tmpl.New(Value{1, 3, 0, 0}))
+			Expect(idx.Lookup(Int16(3))).To(Equal([]int{0, 1, 2}))
+			Expect(idx.Lookup(Int16(-1))).To(BeEmpty())
+		})
+		It("shall be kept in sync by SortBy", func() {
+			ss.SortBy("key")
+			Expect(idx.Lookup(Int16(3))).To(Equal([]int{2, 3}))
+			Expect(idx.Lookup(Int16(-1))).To(Equal([]int{0}))
+		})
+		It("shall be kept in sync by SetColumn", func() {
+			ss.SetColumn("key", []int16{5, 5, 5, 6})
+			Expect(idx.Lookup(Int16(5))).To(Equal([]int{0, 1, 2}))
+			Expect(idx.Lookup(Int16(3))).To(BeEmpty())
+		})
+		It("shall support bit fields", func() {
+			Expect(ss.Index("prio").Lookup(Uint8(1))).To(Equal([]int{0, 3}))
+		})
+	})
+})
@@ -0,0 +1,231 @@
+package bmstruct
+
+import (
+	"fmt"
+	"io"
+)
+
+//PartialRecordError is returned by Decoder and Encoder when only a part of a
+//record could be read or written, e.g. when the input stream ends in the middle
+//of a record.
+type PartialRecordError struct {
+	//Offset is the position of the partial record in the stream.
+	Offset int64
+	//Len is the number of bytes of the record that were read or written.
+	Len int
+	//Size is the expected size of the record, i.e. the Template size or the
+	//size calculated from the size fields of a Template with dynamic fields.
+	Size int
+	//Err is the underlying error, if any.
+	Err error
+}
+
+//Error implements the error interface.
+func (e *PartialRecordError) Error() string {
+	msg := fmt.Sprintf("partial record at offset %d: %d of %d bytes",
+		e.Offset, e.Len, e.Size)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+//Unwrap returns the underlying error.
+func (e *PartialRecordError) Unwrap() error {
+	return e.Err
+}
+
+//Decoder reads records described by a Template from an io.Reader. For a
+//Template with dynamic fields, the size of each record is calculated from its
+//size fields.
+type Decoder struct {
+	r      io.Reader
+	t      *Template
+	offset int64
+	reuse  bool
+	s      *Struct
+}
+
+//NewDecoder creates a new Decoder that reads the records of Template t from r.
+func NewDecoder(r io.Reader, t *Template) *Decoder {
+	return &Decoder{
+		r: r,
+		t: t,
+	}
+}
+
+//ReuseBuffer method turns buffer reuse on or off. When it is on, Decode returns
+//the same Struct object backed by the same byte slice every time, so the
+//returned Struct is only valid until the next call of Decode. By default,
+//buffer reuse is off and every record is read into a newly allocated Struct.
+func (d *Decoder) ReuseBuffer(reuse bool) {
+	d.reuse = reuse
+}
+
+//Offset method returns the number of bytes consumed from the underlying reader.
+func (d *Decoder) Offset() int64 {
+	return d.offset
+}
+
+//Decode method reads the next record. It returns io.EOF when there are no more
+//records and a *PartialRecordError when the stream ends in the middle of a
+//record. It returns an error wrapping ErrRecordTooLarge if the size fields
+//describe a record that cannot be addressed. Other errors of the underlying
+//reader are returned as they are.
+//
+//The buffer of a record is extended gradually as its data arrives, so a size
+//field does not make Decode allocate more memory than the stream contains.
+func (d *Decoder) Decode() (*Struct, error) {
+	s := d.s
+	if !d.reuse || s == nil {
+		s = d.t.Empty()
+	} else {
+		s.Value = s.Value[:d.t.Size]
+	}
+	if d.reuse {
+		d.s = s
+	}
+	n, err := io.ReadFull(d.r, s.Value)
+	offset := d.offset
+	d.offset += int64(n)
+	size := d.t.Size
+	for err == nil && d.t.dynamic() {
+		l := d.t.layout(s.Value)
+		if l.err != nil {
+			return nil, l.err
+		}
+		if size = l.size; l.complete && size == len(s.Value) {
+			break
+		}
+		read := len(s.Value)
+		s.Value = grow(s.Value, read+growth(read, size-read))
+		var m int
+		m, err = io.ReadFull(d.r, s.Value[read:])
+		n += m
+		d.offset += int64(m)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	switch {
+	case err == io.ErrUnexpectedEOF:
+		return nil, &PartialRecordError{
+			Offset: offset,
+			Len:    n,
+			Size:   size,
+			Err:    err,
+		}
+	case err != nil:
+		return nil, err
+	}
+	return s, nil
+}
+
+// minGrowth is the number of bytes a record buffer may be extended by at once
+// even if the buffer is shorter, see growth.
+const minGrowth = 64 << 10
+
+// growth returns the number of bytes to extend a buffer of length n by when
+// missing more bytes are needed: the missing bytes, but at most n or
+// minGrowth, whichever is larger.
+func growth(n, missing int) int {
+	limit := n
+	if limit < minGrowth {
+		limit = minGrowth
+	}
+	if missing > limit {
+		return limit
+	}
+	return missing
+}
+
+// grow returns v extended to size bytes. The underlying array is reused if it is
+// large enough.
+func grow(v Value, size int) Value {
+	if cap(v) >= size {
+		return v[:size]
+	}
+	grown := make(Value, size)
+	copy(grown, v)
+	return grown
+}
+
+//Iter method calls fn for each record until the end of the stream. It returns
+//nil at the end of the stream, otherwise the error of Decode.
+func (d *Decoder) Iter(fn func(s *Struct)) error {
+	for {
+		s, err := d.Decode()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fn(s)
+	}
+}
+
+//Encoder writes records described by a Template to an io.Writer.
+type Encoder struct {
+	w      io.Writer
+	t      *Template
+	offset int64
+}
+
+//NewEncoder creates a new Encoder that writes the records of Template t to w.
+func NewEncoder(w io.Writer, t *Template) *Encoder {
+	return &Encoder{
+		w: w,
+		t: t,
+	}
+}
+
+//Offset method returns the number of bytes written to the underlying writer.
+func (e *Encoder) Offset() int64 {
+	return e.offset
+}
+
+// write writes data that consists of records of the given size.
+func (e *Encoder) write(data []byte, size int) error {
+	n, err := e.w.Write(data)
+	offset := e.offset
+	e.offset += int64(n)
+	if n != len(data) {
+		if err == nil {
+			err = io.ErrShortWrite
+		}
+		if n%size == 0 {
+			return err
+		}
+		return &PartialRecordError{
+			Offset: offset + int64(n-n%size),
+			Len:    n % size,
+			Size:   size,
+			Err:    err,
+		}
+	}
+	return err
+}
+
+//Encode method writes a single Struct. It returns an error if the Template of
+//the Struct differs from the Template of the Encoder and a *PartialRecordError
+//if the Struct could be written only partially. If no byte of the Struct could
+//be written, the error of the writer is returned as it is.
+func (e *Encoder) Encode(s *Struct) error {
+	if !e.t.Equal(s.Template) {
+		return fmt.Errorf("struct with different kind of template cannot be encoded")
+	}
+	return e.write(s.Value, len(s.Value))
+}
+
+//EncodeStructs method writes all Struct objects of a Structs. It returns an
+//error if the Template of the Structs differs from the Template of the Encoder
+//and a *PartialRecordError if the last record could be written only partially.
+//If the write stops at the boundary of two records, the error of the writer is
+//returned as it is.
+func (e *Encoder) EncodeStructs(ss *Structs) error {
+	if !e.t.Equal(ss.Template) {
+		return fmt.Errorf("structs with different kind of template cannot be encoded")
+	}
+	return e.write(ss.Value, e.t.Size)
+}
@@ -0,0 +1,153 @@
+package bmstruct
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type limitedWriter struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.buf.Len()+len(p) > w.limit {
+		p = p[:w.limit-w.buf.Len()]
+	}
+	return w.buf.Write(p)
+}
+
+var _ = Describe("Stream", func() {
+	var tmpl *Template
+
+	BeforeEach(func() {
+		tmpl = NewTemplate(3,
+			Uint8Field("id", 0),
+			Uint16Field("value", 1),
+		)
+	})
+	Describe("Decoder", func() {
+		It("should decode all records", func() {
+			d := NewDecoder(bytes.NewReader([]byte{1, 2, 0, 3, 4, 0}), tmpl)
+			s1, err := d.Decode()
+			Expect(err).NotTo(HaveOccurred())
+			s2, err := d.Decode()
+			Expect(err).NotTo(HaveOccurred())
+			_, err = d.Decode()
+			Expect(err).To(Equal(io.EOF))
+			Expect(s1.Lookup("value").Uint16()).To(Equal(uint16(2)))
+			Expect(s2.Lookup("value").Uint16()).To(Equal(uint16(4)))
+			Expect(d.Offset()).To(Equal(int64(6)))
+		})
+		It("should reuse the buffer when requested", func() {
+			d := NewDecoder(bytes.NewReader([]byte{1, 2, 0, 3, 4, 0}), tmpl)
+			d.ReuseBuffer(true)
+			s1, err := d.Decode()
+			Expect(err).NotTo(HaveOccurred())
+			s2, err := d.Decode()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s1).To(BeIdenticalTo(s2))
+			Expect(s1.Lookup("id").Uint8()).To(Equal(uint8(3)))
+		})
+		It("should report a partial trailing record", func() {
+			d := NewDecoder(bytes.NewReader([]byte{1, 2, 0, 3}), tmpl)
+			ids := []uint8{}
+			err := d.Iter(func(s *Struct) {
+				ids = append(ids, s.Lookup("id").Uint8())
+			})
+			Expect(ids).To(Equal([]uint8{1}))
+			var partial *PartialRecordError
+			Expect(errors.As(err, &partial)).To(BeTrue())
+			Expect(*partial).To(Equal(PartialRecordError{
+				Offset: 3,
+				Len:    1,
+				Size:   3,
+				Err:    io.ErrUnexpectedEOF,
+			}))
+			Expect(errors.Is(err, io.ErrUnexpectedEOF)).To(BeTrue())
+		})
+		It("should return nil from Iter at the end of the stream", func() {
+			d := NewDecoder(bytes.NewReader([]byte{}), tmpl)
+			Expect(d.Iter(func(s *Struct) {})).To(Succeed())
+		})
+	})
+	Describe("Decoder with dynamic fields", func() {
+		It("should decode variable-length records", func() {
+			t := NewTemplate(-1,
+				Uint8Field("len", 0),
+				DynamicField("body", 1, "len"),
+				Uint8Field("end", 1),
+			)
+			data := []byte{2, 'h', 'i', 0xff, 0, 0xfe, 3, 'a'}
+			d := NewDecoder(bytes.NewReader(data), t)
+			d.ReuseBuffer(true)
+			s, err := d.Decode()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s.Lookup("body")).To(Equal(Value("hi")))
+			Expect(s.Lookup("end").Uint8()).To(Equal(uint8(0xff)))
+			s, err = d.Decode()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s.Value).To(Equal(Value{0, 0xfe}))
+			_, err = d.Decode()
+			var partial *PartialRecordError
+			Expect(errors.As(err, &partial)).To(BeTrue())
+			Expect(*partial).To(Equal(PartialRecordError{
+				Offset: 6,
+				Len:    2,
+				Size:   5,
+				Err:    io.ErrUnexpectedEOF,
+			}))
+			Expect(d.Offset()).To(Equal(int64(8)))
+		})
+		It("should check the size fields", func() {
+			t := NewTemplate(-1, Uint64Field("len", 0), DynamicField("body", 8, "len"))
+			huge := Uint64(0x8000000000000008).GetValue()
+			_, err := NewDecoder(bytes.NewReader(append(huge, 1, 2, 3)), t).Decode()
+			Expect(errors.Is(err, ErrRecordTooLarge)).To(BeTrue())
+			d := NewDecoder(bytes.NewReader(append(Uint64(1<<30).GetValue(), 1, 2, 3)), t)
+			_, err = d.Decode()
+			var partial *PartialRecordError
+			Expect(errors.As(err, &partial)).To(BeTrue())
+			Expect(partial.Len).To(Equal(11))
+			Expect(partial.Size).To(Equal(8 + 1<<30))
+		})
+	})
+	Describe("Encoder", func() {
+		It("should encode Structs", func() {
+			var buf bytes.Buffer
+			e := NewEncoder(&buf, tmpl)
+			Expect(e.Encode(tmpl.New(Value{1, 2, 0}))).To(Succeed())
+			Expect(e.EncodeStructs(tmpl.Slice(Value{3, 4, 0, 5, 6, 0}))).To(Succeed())
+			Expect(buf.Bytes()).To(Equal([]byte{1, 2, 0, 3, 4, 0, 5, 6, 0}))
+			Expect(e.Offset()).To(Equal(int64(9)))
+		})
+		It("should reject Structs of a different Template", func() {
+			e := NewEncoder(&bytes.Buffer{}, tmpl)
+			other := NewTemplate(3, Uint8Field("id", 0))
+			Expect(e.Encode(other.Empty())).NotTo(Succeed())
+			Expect(e.EncodeStructs(other.Slice(Value{0, 0, 0}))).NotTo(Succeed())
+		})
+		It("should report a partially written record", func() {
+			w := &limitedWriter{limit: 4}
+			e := NewEncoder(w, tmpl)
+			err := e.EncodeStructs(tmpl.Slice(Value{3, 4, 0, 5, 6, 0}))
+			var partial *PartialRecordError
+			Expect(errors.As(err, &partial)).To(BeTrue())
+			Expect(partial.Offset).To(Equal(int64(3)))
+			Expect(partial.Len).To(Equal(1))
+			Expect(errors.Is(err, io.ErrShortWrite)).To(BeTrue())
+		})
+		It("should return the error of the writer at a record boundary", func() {
+			w := &limitedWriter{limit: 3}
+			e := NewEncoder(w, tmpl)
+			err := e.EncodeStructs(tmpl.Slice(Value{3, 4, 0, 5, 6, 0}))
+			Expect(err).To(Equal(io.ErrShortWrite))
+			Expect(e.Offset()).To(Equal(int64(3)))
+			Expect(e.Encode(tmpl.New(Value{1, 2, 0}))).To(Equal(io.ErrShortWrite))
+		})
+	})
+})
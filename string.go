@@ -0,0 +1,181 @@
+package bmstruct
+
+import (
+	"bytes"
+	"fmt"
+)
+
+//StringField function creates a new Field of StringKind with the given name,
+//offset and capacity in bytes, e.g. for a char name[32] member of a C struct.
+//The string is stored at the beginning of the field and the rest of the field
+//is filled with the padding byte, typically 0 (NUL) or ' ' (space).
+//
+//The Get method of Struct returns the string up to the first NUL character
+//with the trailing padding removed. The Set method returns an error wrapping
+//ErrOverflow if the string is longer than the capacity, unless the Truncate
+//option of the Field is set, in which case the string is truncated at a
+//character boundary.
+//
+//The Charset option of the Field is the name of the Charset that encodes the
+//characters, see RegisterCharset. The default is UTF-8. The padding byte is
+//interpreted as a character and encoded in the Charset, e.g. ' ' is stored as
+//0x40 in EBCDIC.
+func StringField(name string, offset, capacity uint64, padding byte) *Field {
+	return &Field{
+		Name:    name,
+		Offset:  offset,
+		Len:     capacity,
+		Kind:    StringKind,
+		Padding: padding,
+	}
+}
+
+//UTF16StringField function creates a new Field of StringKind with the given
+//name, offset and capacity in bytes that stores a NUL padded UTF-16LE or, if
+//bigEndian is true, UTF-16BE string. See StringField for the details.
+func UTF16StringField(name string, offset, capacity uint64, bigEndian bool) *Field {
+	field := StringField(name, offset, capacity, 0)
+	field.Charset = "utf-16le"
+	if bigEndian {
+		field.Charset = "utf-16be"
+	}
+	return field
+}
+
+//PascalStringField function creates a new Field of StringKind with the given
+//name and offset that stores a string prefixed with its length in bytes. The
+//length is an unsigned little-endian integer of prefix bytes, which shall be 1,
+//2 or 4. The length of the Field is prefix+capacity, the bytes after the string
+//are filled with zeros. See StringField for the options.
+//
+//PascalStringField panics for an invalid prefix or if the capacity cannot be
+//represented in the prefix.
+func PascalStringField(name string, offset uint64, prefix uint8, capacity uint64) *Field {
+	if prefix != 1 && prefix != 2 && prefix != 4 {
+		panic(fmt.Sprintf("invalid length prefix of %d bytes", prefix))
+	}
+	if capacity >= uint64(1)<<(8*prefix) {
+		panic(fmt.Sprintf("capacity %d does not fit into a %d bytes long prefix",
+			capacity, prefix))
+	}
+	return &Field{
+		Name:   name,
+		Offset: offset,
+		Len:    uint64(prefix) + capacity,
+		Kind:   StringKind,
+		Prefix: prefix,
+	}
+}
+
+//DynamicStringField function creates a new dynamic Field of StringKind whose
+//length in bytes is stored in the integer field indicated by lenField, e.g. a
+//variable-length Pascal string is
+//
+//  Uint8Field("name.len", 0),
+//  DynamicStringField("name", 1, "name.len"),
+//
+//Setting the string resizes the Struct and updates the length field, see
+//DynamicField.
+func DynamicStringField(name string, offset uint64, lenField string) *Field {
+	field := DynamicField(name, offset, lenField)
+	field.Kind = StringKind
+	return field
+}
+
+// stringOf returns the string stored in the string field. It panics if the
+// charset of the field is unknown.
+func (f *Field) stringOf(data []byte) string {
+	c, err := lookupCharset(f.Charset)
+	if err != nil {
+		panic(err.Error())
+	}
+	unit := c.UnitLen()
+	b := f.slice(data)
+	if f.Prefix != 0 {
+		n := uintOfBytes(b[:f.Prefix])
+		b = b[f.Prefix:]
+		if n < uint64(len(b)) {
+			b = b[:n]
+		}
+		return c.Decode(b[:len(b)-len(b)%unit])
+	}
+	b = b[:len(b)-len(b)%unit]
+	for i := 0; i < len(b); i += unit {
+		if isZero(b[i : i+unit]) {
+			b = b[:i]
+			break
+		}
+	}
+	if f.Padding != 0 {
+		padding := c.padding[f.Padding]
+		for bytes.HasSuffix(b, padding) {
+			b = b[:len(b)-len(padding)]
+		}
+	}
+	return c.Decode(b)
+}
+
+// isZero returns true if all bytes of b are zero.
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// truncate returns the longest prefix of b, the encoded form of s, that is not
+// longer than n bytes. Characters are never split.
+func truncate(c Charset, s string, b []byte, n int) []byte {
+	i := 0
+	for _, r := range s {
+		l := c.RuneLen(r)
+		if l < 0 || i+l > n {
+			break
+		}
+		i += l
+	}
+	return b[:i]
+}
+
+// encodeString returns the Value of the string field.
+func (f *Field) encodeString(s string) (Value, error) {
+	c, err := lookupCharset(f.Charset)
+	if err != nil {
+		return nil, err
+	}
+	b, err := c.Encode(s)
+	if err != nil {
+		return nil, err
+	}
+	if f.LenField != "" {
+		return b, nil
+	}
+	unit := c.UnitLen()
+	if f.Prefix == 0 {
+		for i := 0; i+unit <= len(b); i += unit {
+			if isZero(b[i : i+unit]) {
+				return nil, fmt.Errorf("string contains NUL at %d", i)
+			}
+		}
+	}
+	capacity := int(f.Len) - int(f.Prefix)
+	if len(b) > capacity {
+		if !f.Truncate {
+			return nil, fmt.Errorf("string of %d bytes longer than %d: %w",
+				len(b), capacity, ErrOverflow)
+		}
+		b = truncate(c, s, b, capacity)
+	}
+	value := make(Value, f.Len)
+	putUintBytes(value[:f.Prefix], uint64(len(b)))
+	copy(value[f.Prefix:], b)
+	if f.Prefix == 0 && f.Padding != 0 {
+		padding := c.padding[f.Padding]
+		for i := len(b); i+len(padding) <= len(value); i += len(padding) {
+			copy(value[i:], padding)
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,136 @@
+package bmstruct
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("String fields", func() {
+	var tmpl *Template
+	var s *Struct
+
+	BeforeEach(func() {
+		truncated := StringField("short", 14, 4, 0)
+		truncated.Truncate = true
+		ascii := StringField("ascii", 18, 4, 0)
+		ascii.Charset = "ascii"
+		tmpl = NewTemplate(-1,
+			StringField("name", 0, 8, 0),
+			StringField("label", 8, 6, ' '),
+			truncated,
+			ascii,
+		)
+		s = tmpl.Empty()
+	})
+	It("should pad the strings", func() {
+		Expect(s.Set("name", "abc")).To(Succeed())
+		Expect(s.Set("label", "xy")).To(Succeed())
+		Expect(s.Lookup("name")).To(Equal(Value{'a', 'b', 'c', 0, 0, 0, 0, 0}))
+		Expect(s.Lookup("label")).To(Equal(Value("xy    ")))
+		Expect(s.Get("name")).To(Equal("abc"))
+		Expect(s.Get("label")).To(Equal("xy"))
+		Expect(s.Set("name", "12345678")).To(Succeed())
+		Expect(s.Get("name")).To(Equal("12345678"))
+	})
+	It("should stop at the first NUL", func() {
+		s.Update("name", Value{'h', 'i', 0, 'x', 'y', 0, 0, 0})
+		Expect(s.Get("name")).To(Equal("hi"))
+		s.Update("label", Value{'o', 'k', ' ', 0, 'z', ' '})
+		Expect(s.Get("label")).To(Equal("ok"))
+	})
+	It("should report too long strings", func() {
+		err := s.Set("name", "123456789")
+		Expect(errors.Is(err, ErrOverflow)).To(BeTrue())
+		Expect(s.Set("name", "a\x00b")).NotTo(Succeed())
+		Expect(s.Get("name")).To(Equal(""))
+	})
+	It("should truncate at character boundaries", func() {
+		Expect(s.Set("short", "abcdef")).To(Succeed())
+		Expect(s.Get("short")).To(Equal("abcd"))
+		Expect(s.Set("short", "aéé")).To(Succeed())
+		Expect(s.Get("short")).To(Equal("aé"))
+	})
+	It("should check the charset", func() {
+		Expect(s.Set("ascii", "abc")).To(Succeed())
+		Expect(s.Set("ascii", "é")).NotTo(Succeed())
+		Expect(s.Set("name", "\xff")).NotTo(Succeed())
+	})
+	Describe("length-prefixed strings", func() {
+		It("should store the length before the string", func() {
+			t := NewTemplate(-1,
+				PascalStringField("name", 0, 1, 5),
+				PascalStringField("wide", 6, 2, 4),
+			)
+			s := t.Empty()
+			Expect(s.Set("name", "abc")).To(Succeed())
+			Expect(s.Lookup("name")).To(Equal(Value{3, 'a', 'b', 'c', 0, 0}))
+			Expect(s.Get("name")).To(Equal("abc"))
+			Expect(s.Set("name", "a\x00b")).To(Succeed())
+			Expect(s.Get("name")).To(Equal("a\x00b"))
+			Expect(errors.Is(s.Set("name", "abcdef"), ErrOverflow)).To(BeTrue())
+			Expect(s.Set("wide", "")).To(Succeed())
+			Expect(s.Get("wide")).To(Equal(""))
+			s.Update("name", Value{200, 'x', 'y', 0, 0, 0})
+			Expect(s.Get("name")).To(Equal("xy\x00\x00\x00"))
+		})
+		It("should panic for invalid prefixes", func() {
+			Expect(func() {
+				PascalStringField("name", 0, 3, 5)
+			}).To(Panic())
+			Expect(func() {
+				PascalStringField("name", 0, 1, 256)
+			}).To(Panic())
+		})
+		It("should have variable length", func() {
+			t := NewTemplate(-1,
+				Uint8Field("name.len", 0),
+				DynamicStringField("name", 1, "name.len"),
+				Uint8Field("end", 1),
+			)
+			s := t.Empty()
+			Expect(s.Set("name", "hello")).To(Succeed())
+			Expect(s.Value).To(Equal(Value{5, 'h', 'e', 'l', 'l', 'o', 0}))
+			Expect(s.Get("name")).To(Equal("hello"))
+			long := string(make([]byte, 256))
+			Expect(errors.Is(s.Set("name", long), ErrOverflow)).To(BeTrue())
+		})
+	})
+	Describe("UTF-16 strings", func() {
+		It("should encode little- and big-endian strings", func() {
+			t := NewTemplate(-1,
+				UTF16StringField("le", 0, 8, false),
+				UTF16StringField("be", 8, 8, true),
+			)
+			s := t.Empty()
+			Expect(s.Set("le", "añ")).To(Succeed())
+			Expect(s.Set("be", "añ")).To(Succeed())
+			Expect(s.Lookup("le")).To(Equal(Value{'a', 0, 0xf1, 0, 0, 0, 0, 0}))
+			Expect(s.Lookup("be")).To(Equal(Value{0, 'a', 0, 0xf1, 0, 0, 0, 0}))
+			Expect(s.Get("le")).To(Equal("añ"))
+			Expect(s.Get("be")).To(Equal("añ"))
+		})
+		It("should not split surrogate pairs", func() {
+			f := UTF16StringField("name", 0, 6, false)
+			f.Truncate = true
+			s := NewTemplate(-1, f).Empty()
+			Expect(s.Set("name", "ab\U0001F600")).To(Succeed())
+			Expect(s.Get("name")).To(Equal("ab"))
+			Expect(s.Set("name", "a\U0001F600")).To(Succeed())
+			Expect(s.Get("name")).To(Equal("a\U0001F600"))
+		})
+		It("should support length prefixes and padding", func() {
+			pascal := PascalStringField("name", 0, 2, 6)
+			pascal.Charset = "utf-16be"
+			padded := StringField("label", 8, 6, ' ')
+			padded.Charset = "utf-16le"
+			s := NewTemplate(-1, pascal, padded).Empty()
+			Expect(s.Set("name", "ok")).To(Succeed())
+			Expect(s.Set("label", "x")).To(Succeed())
+			Expect(s.Value).To(Equal(Value{4, 0, 0, 'o', 0, 'k', 0, 0, 'x', 0, ' ', 0, ' ', 0}))
+			Expect(s.Get("name")).To(Equal("ok"))
+			Expect(s.Get("label")).To(Equal("x"))
+		})
+	})
+})
@@ -1,7 +1,10 @@
 package bmstruct
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 )
 
 //Struct is a Template with an associated Value. You can think of a Struct as an
@@ -11,14 +14,74 @@ type Struct struct {
 	Value     `json:"data"`
 }
 
+// structJSON is the JSON representation of Struct and Structs. Without the
+// explicit marshaling methods, the JSON methods of the embedded Template would be
+// promoted and used for the whole object. Fields is used by Struct only.
+type structJSON struct {
+	Template *Template       `json:"template"`
+	Value    Value           `json:"data"`
+	Fields   json.RawMessage `json:"fields,omitempty"`
+}
+
+//MarshalJSON implements the json.Marshaler interface. Besides the Template and
+//the data, the JSON object contains the present fields of the Struct under
+//"fields" in declaration order, converted like Get converts them: enum fields
+//are represented by the names of their values, flags fields by the names of
+//their bits like "SYN|ACK", decimals, 128-bit and big integers as strings,
+//nested Templates as objects and the fields returned as Value by Get as base64
+//strings. Decimal, numeric text and strict bool fields with invalid content
+//are null.
+func (s Struct) MarshalJSON() ([]byte, error) {
+	raw := structJSON{
+		Template: s.Template,
+		Value:    s.Value,
+	}
+	if s.Template != nil {
+		fields, err := s.marshalFields()
+		if err != nil {
+			return nil, err
+		}
+		raw.Fields = fields
+	}
+	return json.Marshal(raw)
+}
+
+//UnmarshalJSON implements the json.Unmarshaler interface. The fields, if any,
+//are set by Set after the data, so they can be edited in the JSON object. If
+//the data is missing, the fields are set in an empty Struct, see
+//Template.Empty. Null fields are ignored.
+func (s *Struct) UnmarshalJSON(b []byte) error {
+	var raw structJSON
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	s.Template, s.Value = raw.Template, raw.Value
+	if len(raw.Fields) == 0 {
+		return nil
+	}
+	if s.Template == nil {
+		return fmt.Errorf("fields cannot be decoded without a template")
+	}
+	if s.Value == nil {
+		s.Value = s.Template.Empty().Value
+	}
+	return s.unmarshalFields(raw.Fields)
+}
+
 //New method instantiates a Struct object by mapping the given data to the
 //Template.
 //
 //The size of the given data shall exactly match the Template size otherwise it
-//will panic.
+//will panic. For a Template with dynamic fields, the size of the data shall
+//match the size calculated from the size fields.
 func (t *Template) New(data Valuable) *Struct {
 	value := data.GetValue()
-	if t.Size != len(value) {
+	size, complete := t.Size, true
+	if t.dynamic() {
+		l := t.layout(value)
+		size, complete = l.size, l.complete
+	}
+	if !complete || size != len(value) {
 		panic("data bytes does not match the template size")
 	}
 	return &Struct{
@@ -46,16 +109,65 @@ func (s *Struct) Clone() *Struct {
 	return clone
 }
 
+// resolve returns the Field indicated by fieldName. It panics if the field does
+// not exist or refers to an inactive case of a variant.
+func (s *Struct) resolve(fieldName string) resolved {
+	r, err := s.Template.resolve(s.Value, fieldName)
+	if err != nil {
+		panic(err.Error())
+	}
+	return r
+}
+
+// resolvePresent returns the Field indicated by fieldName or nil if it is an
+// absent optional field. It panics if the field does not exist or refers to an
+// inactive case of a variant.
+func (s *Struct) resolvePresent(fieldName string) *Field {
+	r, err := s.Template.resolve(s.Value, fieldName)
+	if errors.Is(err, ErrFieldAbsent) {
+		return nil
+	}
+	if err != nil {
+		panic(err.Error())
+	}
+	return r.field
+}
+
+// fieldFunc returns a function that returns the Field indicated by fieldName or
+// nil if it is an absent optional field. Paths referring to nested fields and
+// the fields whose position depends on dynamic fields are resolved on every
+// call as the active case of a variant or the layout of the record may change.
+func (s *Struct) fieldFunc(fieldName string) func() *Field {
+	field := s.resolvePresent(fieldName)
+	if original, found := s.Template.Fields[fieldName]; found && !original.dynamic() &&
+		!s.Template.shifted(original) {
+		return func() *Field {
+			return field
+		}
+	}
+	return func() *Field {
+		return s.resolvePresent(fieldName)
+	}
+}
+
 // Lookup method of Struct returns the Value if the field indicated by
 // fieldName. A clone of the field is returned so modifying the returned value
 // does not impact the Struct. For modifying the Struct object use the Update or
 // UpdateFunc method.
 //
-// Lookup operation for a non-existing field name will panic.
+// The fieldName may be a dot separated path of a field in a nested Template,
+// e.g. "grades.math", or in the active case of a variant field, e.g.
+// "payload.icmp.type".
+//
+// Lookup returns nil for an absent optional field (see OptionalField). Use
+// LookupE to get a distinguishable error instead.
+//
+// Lookup operation for a non-existing field name or for a field of an inactive
+// variant case will panic.
 func (s *Struct) Lookup(fieldName string) Value {
-	field, found := s.Template.Fields[fieldName]
-	if !found {
-		panic(fmt.Sprintf("field name %s not found in template", fieldName))
+	field := s.resolvePresent(fieldName)
+	if field == nil {
+		return nil
 	}
 	return field.copySlice(s.Value)
 }
@@ -67,30 +179,46 @@ func (s *Struct) Lookup(fieldName string) Value {
 //
 // LookupFunc operation for a non-existing field name will panic.
 func (s *Struct) LookupFunc(fieldName string) func() Value {
-	field, found := s.Template.Fields[fieldName]
-	if !found {
-		panic(fmt.Sprintf("field name %s not found in template", fieldName))
-	}
+	fieldFn := s.fieldFunc(fieldName)
 	return func() Value {
+		field := fieldFn()
+		if field == nil {
+			return nil
+		}
 		return field.copySlice(s.Value)
 	}
 }
 
 // Update method of Struct changes the field indicated by fieldName to the given
-// Value.
+// Value. The fieldName may be a path as described at the Lookup method.
+//
+// Updating a dynamic field (see DynamicField) with a Value of different size
+// resizes the Struct and changes the size field of the dynamic field
+// accordingly. Updating an absent optional field (see OptionalField) inserts
+// the field and sets its presence field to 1. Conversely, updating the size
+// field of a dynamic field truncates the dynamic field or extends it with
+// zeros, and updating the presence field of an optional field removes the
+// field or inserts it filled with zeros. As the data of the Struct is
+// reallocated in these cases, the Struct no longer refers to the data it was
+// created from.
 //
 // Update operation will panic for a non-existing field name or incorrect Value
 // size.
 func (s *Struct) Update(fieldName string, valuable Valuable) {
 	value := valuable.GetValue()
-	field, found := s.Template.Fields[fieldName]
-	if !found {
-		panic(fmt.Sprintf("field name %s not found in template", fieldName))
+	if original, found := s.Template.Fields[fieldName]; found && original.dynamic() {
+		s.resize(original, value)
+		return
 	}
+	field := s.resolve(fieldName).field
 	if uint64(len(value)) != field.Len {
 		panic(fmt.Sprintf("new value size (%d bytes) and field length (%d bytes) mismatch",
 			uint64(len(value)), field.Len))
 	}
+	if dependents := s.Template.fieldOrder().dependents[fieldName]; len(dependents) > 0 {
+		s.updateSize(field, value, dependents)
+		return
+	}
 	field.updateSlice(s.Value, value)
 }
 
@@ -100,16 +228,25 @@ func (s *Struct) Update(fieldName string, valuable Valuable) {
 // UpdateFunc operation will panic for a non-existing field name and the
 // returned function will panic for incorrect value size.
 func (s *Struct) UpdateFunc(fieldName string) func(valuable Valuable) {
-	field, found := s.Template.Fields[fieldName]
-	if !found {
-		panic(fmt.Sprintf("field name %s not found in template", fieldName))
-	}
+	fieldFn := s.fieldFunc(fieldName)
 	return func(valuable Valuable) {
 		value := valuable.GetValue()
+		if original, found := s.Template.Fields[fieldName]; found && original.dynamic() {
+			s.resize(original, value)
+			return
+		}
+		field := fieldFn()
+		if field == nil {
+			panic(fmt.Sprintf("field %s: %s", fieldName, ErrFieldAbsent))
+		}
 		if uint64(len(value)) != field.Len {
 			panic(fmt.Sprintf("new value size (%d bytes) and field length (%d bytes) mismatch",
 				uint64(len(value)), field.Len))
 		}
+		if dependents := s.Template.fieldOrder().dependents[fieldName]; len(dependents) > 0 {
+			s.updateSize(field, value, dependents)
+			return
+		}
 		field.updateSlice(s.Value, value)
 	}
 }
@@ -118,12 +255,36 @@ func (s *Struct) UpdateFunc(fieldName string) func(valuable Valuable) {
 type Structs struct {
 	*Template `json:"template"`
 	Value     `json:"data"`
+
+	indexes []*Index
+}
+
+//MarshalJSON implements the json.Marshaler interface.
+func (ss Structs) MarshalJSON() ([]byte, error) {
+	return json.Marshal(structJSON{
+		Template: ss.Template,
+		Value:    ss.Value,
+	})
+}
+
+//UnmarshalJSON implements the json.Unmarshaler interface.
+func (ss *Structs) UnmarshalJSON(b []byte) error {
+	var raw structJSON
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	ss.Template, ss.Value, ss.indexes = raw.Template, raw.Value, nil
+	return nil
 }
 
 //Slice method creates a new Structs object. Slice will panic when the length of
-//the given data does not align with the size of the Template.
+//the given data does not align with the size of the Template or when the
+//Template has dynamic fields.
 func (t *Template) Slice(data Valuable) *Structs {
 	value := data.GetValue()
+	if t.dynamic() {
+		panic("template with dynamic fields cannot be sliced")
+	}
 	if len(value)%t.Size != 0 {
 		panic("data bytes does not align")
 	}
@@ -135,9 +296,13 @@ func (t *Template) Slice(data Valuable) *Structs {
 }
 
 //SliceE method creates a new Structs object. SliceE will return an error when
-//the length of the given data does not align with the size of the Template.
+//the length of the given data does not align with the size of the Template or
+//when the Template has dynamic fields.
 func (t *Template) SliceE(data Valuable) (*Structs, error) {
 	value := data.GetValue()
+	if t.dynamic() {
+		return nil, fmt.Errorf("template with dynamic fields cannot be sliced")
+	}
 	if len(value)%t.Size != 0 {
 		return nil, fmt.Errorf("data bytes does not align")
 	}
@@ -203,7 +368,14 @@ func (ss *Structs) Update(offset uint64, s *Struct) {
 	if !ss.Template.Equal(s.Template) {
 		panic("structs cannot be updated with different kind of struct")
 	}
+	var oldData Value
+	if len(ss.indexes) != 0 {
+		oldData = ss.Value[offset : offset+uint64(ss.Template.Size)].Clone()
+	}
 	copy(ss.Value[offset:offset+(uint64(ss.Template.Size))], s.Value)
+	if oldData != nil {
+		ss.updateIndexes(int(offset/uint64(ss.Template.Size)), oldData)
+	}
 }
 
 //Clone method returns a new Structs that is the clone of the original Struct,
@@ -227,3 +399,252 @@ func (ss *Structs) Iter(fn StructsIterFn) {
 		fn(offset, ss.At(offset))
 	}
 }
+
+// row returns the data of the nth Struct object without copying it.
+func (ss *Structs) row(n int) []byte {
+	offset := n * ss.Template.Size
+	return ss.Value[offset : offset+ss.Template.Size]
+}
+
+//Column method returns the values of the field indicated by fieldName from
+//every Struct object in a single typed slice. The type of the returned slice
+//depends on the Kind of the field, e.g. a Uint32Field results in []uint32. Bit
+//fields result in []uint8, bool fields in []bool and the other fields, e.g.
+//fields of BytesKind, string and decimal fields, in []Value.
+//
+//The returned slice is a copy, modifying it does not impact the Structs. For
+//modifying the Structs use the SetColumn method.
+//
+//Column panics for a non-existing field name.
+func (ss *Structs) Column(fieldName string) interface{} {
+	field := ss.Template.lookupField(fieldName)
+	n := int(ss.Count())
+	switch field.Kind {
+	case Uint8Kind:
+		column := make([]uint8, n)
+		for i := range column {
+			column[i] = uint8(field.uintOf(ss.row(i)))
+		}
+		return column
+	case Int8Kind:
+		column := make([]int8, n)
+		for i := range column {
+			column[i] = int8(field.intOf(ss.row(i)))
+		}
+		return column
+	case Uint16Kind:
+		column := make([]uint16, n)
+		for i := range column {
+			column[i] = uint16(field.uintOf(ss.row(i)))
+		}
+		return column
+	case Int16Kind:
+		column := make([]int16, n)
+		for i := range column {
+			column[i] = int16(field.intOf(ss.row(i)))
+		}
+		return column
+	case Uint32Kind:
+		column := make([]uint32, n)
+		for i := range column {
+			column[i] = uint32(field.uintOf(ss.row(i)))
+		}
+		return column
+	case Int32Kind:
+		column := make([]int32, n)
+		for i := range column {
+			column[i] = int32(field.intOf(ss.row(i)))
+		}
+		return column
+	case Uint64Kind:
+		column := make([]uint64, n)
+		for i := range column {
+			column[i] = field.uintOf(ss.row(i))
+		}
+		return column
+	case Int64Kind:
+		column := make([]int64, n)
+		for i := range column {
+			column[i] = field.intOf(ss.row(i))
+		}
+		return column
+	case UintKind:
+		column := make([]uint, n)
+		for i := range column {
+			column[i] = uint(field.uintOf(ss.row(i)))
+		}
+		return column
+	case IntKind:
+		column := make([]int, n)
+		for i := range column {
+			column[i] = int(field.intOf(ss.row(i)))
+		}
+		return column
+	case UintptrKind:
+		column := make([]uintptr, n)
+		for i := range column {
+			column[i] = uintptr(field.uintOf(ss.row(i)))
+		}
+		return column
+	case Float32Kind:
+		column := make([]float32, n)
+		for i := range column {
+			column[i] = float32(field.floatOf(ss.row(i)))
+		}
+		return column
+	case Float64Kind:
+		column := make([]float64, n)
+		for i := range column {
+			column[i] = field.floatOf(ss.row(i))
+		}
+		return column
+	case BoolKind:
+		column := make([]bool, n)
+		for i := range column {
+			column[i] = field.uintOf(ss.row(i)) != 0
+		}
+		return column
+	default:
+		data := make([]byte, n*int(field.Len))
+		column := make([]Value, n)
+		for i := range column {
+			column[i] = data[i*int(field.Len) : (i+1)*int(field.Len)]
+			copy(column[i], field.slice(ss.row(i)))
+		}
+		return column
+	}
+}
+
+//SetColumn method changes the field indicated by fieldName in every Struct
+//object. The values parameter shall be a slice with the type that the Column
+//method returns for the field and with as many elements as the Structs has.
+//Besides, the content of any field but a bit field can be set with []Value,
+//e.g. of string and decimal fields, for which Column returns []Value too.
+//
+//SetColumn panics for a non-existing field name, for a values parameter of
+//incorrect type or length or for integers that do not fit into the field.
+func (ss *Structs) SetColumn(fieldName string, values interface{}) {
+	field := ss.Template.lookupField(fieldName)
+	n := int(ss.Count())
+	switch column := values.(type) {
+	case []uint8:
+		ss.checkColumn(field, Uint8Kind, n, len(column))
+		checkColumnFits(field, column)
+		for i, v := range column {
+			field.putUint(ss.row(i), uint64(v))
+		}
+	case []int8:
+		ss.checkColumn(field, Int8Kind, n, len(column))
+		checkColumnFits(field, column)
+		for i, v := range column {
+			field.putUint(ss.row(i), uint64(v))
+		}
+	case []uint16:
+		ss.checkColumn(field, Uint16Kind, n, len(column))
+		checkColumnFits(field, column)
+		for i, v := range column {
+			field.putUint(ss.row(i), uint64(v))
+		}
+	case []int16:
+		ss.checkColumn(field, Int16Kind, n, len(column))
+		checkColumnFits(field, column)
+		for i, v := range column {
+			field.putUint(ss.row(i), uint64(v))
+		}
+	case []uint32:
+		ss.checkColumn(field, Uint32Kind, n, len(column))
+		checkColumnFits(field, column)
+		for i, v := range column {
+			field.putUint(ss.row(i), uint64(v))
+		}
+	case []int32:
+		ss.checkColumn(field, Int32Kind, n, len(column))
+		checkColumnFits(field, column)
+		for i, v := range column {
+			field.putUint(ss.row(i), uint64(v))
+		}
+	case []uint64:
+		ss.checkColumn(field, Uint64Kind, n, len(column))
+		checkColumnFits(field, column)
+		for i, v := range column {
+			field.putUint(ss.row(i), v)
+		}
+	case []int64:
+		ss.checkColumn(field, Int64Kind, n, len(column))
+		checkColumnFits(field, column)
+		for i, v := range column {
+			field.putUint(ss.row(i), uint64(v))
+		}
+	case []uint:
+		ss.checkColumn(field, UintKind, n, len(column))
+		checkColumnFits(field, column)
+		for i, v := range column {
+			field.putUint(ss.row(i), uint64(v))
+		}
+	case []int:
+		ss.checkColumn(field, IntKind, n, len(column))
+		checkColumnFits(field, column)
+		for i, v := range column {
+			field.putUint(ss.row(i), uint64(v))
+		}
+	case []uintptr:
+		ss.checkColumn(field, UintptrKind, n, len(column))
+		checkColumnFits(field, column)
+		for i, v := range column {
+			field.putUint(ss.row(i), uint64(v))
+		}
+	case []float32:
+		ss.checkColumn(field, Float32Kind, n, len(column))
+		for i, v := range column {
+			field.putUint(ss.row(i), uint64(math.Float32bits(v)))
+		}
+	case []float64:
+		ss.checkColumn(field, Float64Kind, n, len(column))
+		for i, v := range column {
+			field.putUint(ss.row(i), math.Float64bits(v))
+		}
+	case []bool:
+		ss.checkColumn(field, BoolKind, n, len(column))
+		for i, v := range column {
+			field.putUint(ss.row(i), uint64(Bool(v)[0]))
+		}
+	case []Value:
+		if field.BitFieldLen != 0 || field.dynamic() {
+			panic(fmt.Sprintf("field %s cannot be set with Value values", field.Name))
+		}
+		ss.checkColumn(field, field.Kind, n, len(column))
+		for i, v := range column {
+			field.updateSlice(ss.row(i), v)
+		}
+	default:
+		panic(fmt.Sprintf("column of type %T is not supported", values))
+	}
+	ss.rebuildIndexes()
+}
+
+func (ss *Structs) checkColumn(field *Field, kind Kind, count, length int) {
+	if field.Kind != kind {
+		panic(fmt.Sprintf("field %s of kind %s cannot be set with %s values",
+			field.Name, field.Kind, kind))
+	}
+	if count != length {
+		panic(fmt.Sprintf("column length (%d) and number of structs (%d) mismatch",
+			length, count))
+	}
+}
+
+// checkColumnFits panics if any of the values does not fit into the integer
+// field, e.g. into a bit field or a field created by UintNField.
+func checkColumnFits[T Number](field *Field, column []T) {
+	bits := field.bits()
+	if bits >= 64 {
+		return
+	}
+	signed := field.BitFieldLen == 0 && field.Kind.Signed()
+	for _, v := range column {
+		if signed && int64(v)<<(64-bits)>>(64-bits) != int64(v) ||
+			!signed && uint64(v) >= uint64(1)<<bits {
+			panic(fmt.Sprintf("%v does not fit into field %s", v, field.Name))
+		}
+	}
+}
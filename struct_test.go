@@ -1,6 +1,10 @@
 package bmstruct
 
 import (
+	"encoding/json"
+	"math"
+	"strings"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -60,6 +64,78 @@ var _ = Describe("Struct", func() {
 				Expect(clone.Value[0]).To(Equal(byte(42)))
 			})
 		})
+		Describe("JSON encoding", func() {
+			It("should contain the template and the data", func() {
+				b, err := json.Marshal(s)
+				Expect(err).NotTo(HaveOccurred())
+				var decoded Struct
+				Expect(json.Unmarshal(b, &decoded)).To(Succeed())
+				Expect(decoded.Template.Equal(tmpl)).To(BeTrue())
+				Expect(decoded.Value).To(Equal(s.Value))
+			})
+			It("should contain the fields", func() {
+				b, err := json.Marshal(s)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(b)).To(ContainSubstring(`"fields":{"field1":650777868590383874,"field2":1229499251294997258}`))
+			})
+			It("should set the fields after the data", func() {
+				t, err := json.Marshal(tmpl)
+				Expect(err).NotTo(HaveOccurred())
+				var decoded Struct
+				Expect(json.Unmarshal([]byte(`{"template":`+string(t)+`,"fields":{"field2":-2}}`), &decoded)).To(Succeed())
+				Expect(decoded.Value).To(HaveLen(20))
+				Expect(decoded.Get("field1")).To(Equal(0))
+				Expect(decoded.Get("field2")).To(Equal(-2))
+				Expect(json.Unmarshal([]byte(`{"template":`+string(t)+
+					`,"data":"AAECAwQFBgcICQoLDA0ODxAREhM=","fields":{"field1":7,"field2":null}}`), &decoded)).To(Succeed())
+				Expect(decoded.Get("field1")).To(Equal(7))
+				Expect(decoded.Lookup("field2")).To(Equal(s.Lookup("field2")))
+				Expect(json.Unmarshal([]byte(`{"template":`+string(t)+`,"fields":{"field3":1}}`), &decoded)).NotTo(Succeed())
+				Expect(json.Unmarshal([]byte(`{"template":`+string(t)+`,"fields":{"field1":"x"}}`), &decoded)).NotTo(Succeed())
+			})
+			It("should represent the kinds of fields", func() {
+				point := NewTemplate(-1, Uint8Field("x", 0), Uint8Field("y", 1))
+				t := NewTemplate(-1,
+					Float64Field("ratio", 0),
+					Float32Field("nan", 8),
+					StringField("name", 12, 4, 0),
+					BoolField("ok", 16),
+					point.Field("point", 17),
+					PackedDecimalField("amount", 19, 3, 1, true),
+					&Field{Name: "raw", Offset: 21, Len: 2},
+				)
+				s := t.Empty()
+				Expect(s.Set("ratio", 0.5)).To(Succeed())
+				Expect(s.Set("nan", float32(math.NaN()))).To(Succeed())
+				Expect(s.Set("name", "ab")).To(Succeed())
+				Expect(s.Set("ok", true)).To(Succeed())
+				s.Update("point", Value{1, 2})
+				Expect(s.Set("amount", "-12.5")).To(Succeed())
+				s.Update("raw", Value{0xff, 0xfe})
+				b, err := json.Marshal(s)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(b)).To(ContainSubstring(`"fields":{"ratio":0.5,"nan":"NaN","name":"ab",` +
+					`"ok":true,"point":{"x":1,"y":2},"amount":"-12.5","raw":"//4="}`))
+				var decoded Struct
+				Expect(json.Unmarshal(b, &decoded)).To(Succeed())
+				Expect(decoded.Value).To(Equal(s.Value))
+				b = []byte(strings.Replace(string(b), `"data":"`, `"ignored":"`, 1))
+				decoded = Struct{}
+				Expect(json.Unmarshal(b, &decoded)).To(Succeed())
+				Expect(decoded.Value).To(Equal(s.Value))
+			})
+			It("should return an error for fields that cannot be converted", func() {
+				_, err := json.Marshal(Struct{Template: tmpl, Value: Value{1, 2, 3}})
+				Expect(err).To(HaveOccurred())
+				dynamic := NewTemplate(-1, Uint8Field("len", 0), DynamicField("body", 1, "len"))
+				_, err = json.Marshal(Struct{Template: dynamic, Value: Value{5, 1}})
+				Expect(err).To(HaveOccurred())
+				name := StringField("name", 0, 4, 0)
+				name.Charset = "no-such-charset"
+				_, err = json.Marshal(NewTemplate(-1, name).Empty())
+				Expect(err).To(HaveOccurred())
+			})
+		})
 		Describe("the Lookup method", func() {
 			Context("for non-existing key", func() {
 				It("should panic", func() {
@@ -210,6 +286,16 @@ var _ = Describe("Struct", func() {
 		// 		}))
 		// 	})
 		// })
+		Describe("JSON encoding", func() {
+			It("should contain the template and the data", func() {
+				b, err := json.Marshal(ss)
+				Expect(err).NotTo(HaveOccurred())
+				var decoded Structs
+				Expect(json.Unmarshal(b, &decoded)).To(Succeed())
+				Expect(decoded.Template.Equal(tmpl)).To(BeTrue())
+				Expect(decoded.Count()).To(Equal(uint32(4)))
+			})
+		})
 		Describe("the Count method", func() {
 			It("should report correct number of structs with Count", func() {
 				Expect(ss.Count()).To(Equal(uint32(4)))
@@ -343,4 +429,110 @@ var _ = Describe("Struct", func() {
 			})
 		})
 	})
+	Describe("Columns of Structs", func() {
+		var ss *Structs
+		var sliceData Value
+
+		BeforeEach(func() {
+			colTmpl := NewTemplate(8,
+				Uint32Field("u32", 0),
+				Int16Field("i16", 4),
+				BitField("bf", 6, 2, 3),
+				(&Template{Size: 1}).Field("raw", 7),
+			)
+			sliceData = Value{
+				1, 0, 0, 0, 0xff, 0xff, 0x0c, 7,
+				2, 0, 0, 0, 2, 0, 0x10, 8,
+				3, 1, 0, 0, 3, 0, 0x00, 9,
+			}
+			ss = colTmpl.Slice(sliceData)
+		})
+		Describe("the Column method call", func() {
+			It("shall return typed values", func() {
+				Expect(ss.Column("u32")).To(Equal([]uint32{1, 2, 259}))
+				Expect(ss.Column("i16")).To(Equal([]int16{-1, 2, 3}))
+				Expect(ss.Column("bf")).To(Equal([]uint8{3, 4, 0}))
+				Expect(ss.Column("raw")).To(Equal([]Value{{7}, {8}, {9}}))
+			})
+			It("shall panic for non-existing field", func() {
+				Expect(func() {
+					ss.Column("no-such-field")
+				}).To(Panic())
+			})
+		})
+		Describe("the SetColumn method call", func() {
+			It("shall change the data", func() {
+				ss.SetColumn("u32", []uint32{0x01020304, 0, 1})
+				ss.SetColumn("i16", []int16{-2, 0, 1})
+				ss.SetColumn("bf", []uint8{0, 7, 1})
+				Expect(sliceData).To(Equal(Value{
+					4, 3, 2, 1, 0xfe, 0xff, 0x00, 7,
+					0, 0, 0, 0, 0, 0, 0x1c, 8,
+					1, 0, 0, 0, 1, 0, 0x04, 9,
+				}))
+			})
+			It("shall be the inverse of Column", func() {
+				ss.SetColumn("raw", []Value{{1}, {2}, {3}})
+				Expect(ss.Column("raw")).To(Equal([]Value{{1}, {2}, {3}}))
+				typed := NewTemplate(-1,
+					StringField("name", 0, 2, 0),
+					PackedDecimalField("amount", 2, 3, 0, true),
+				).Slice(make(Value, 8))
+				Expect(typed.Nth(0).Set("name", "ab")).To(Succeed())
+				Expect(typed.Nth(1).Set("amount", -12)).To(Succeed())
+				names, amounts := typed.Column("name"), typed.Column("amount")
+				copied := NewTemplate(-1,
+					StringField("name", 0, 2, 0),
+					PackedDecimalField("amount", 2, 3, 0, true),
+				).Slice(make(Value, 8))
+				copied.SetColumn("name", names)
+				copied.SetColumn("amount", amounts)
+				Expect(copied.Value).To(Equal(typed.Value))
+				Expect(func() {
+					ss.SetColumn("bf", []Value{{1}, {2}, {3}})
+				}).To(Panic())
+			})
+			It("shall panic for values of incorrect type", func() {
+				Expect(func() {
+					ss.SetColumn("u32", []uint16{1, 2, 3})
+				}).To(Panic())
+				Expect(func() {
+					ss.SetColumn("u32", "string")
+				}).To(Panic())
+			})
+			It("shall panic for values of incorrect length", func() {
+				Expect(func() {
+					ss.SetColumn("u32", []uint32{1, 2})
+				}).To(Panic())
+			})
+			It("shall panic for values that do not fit into the field", func() {
+				Expect(func() {
+					ss.SetColumn("bf", []uint8{0, 8, 1})
+				}).To(Panic())
+				odd := NewTemplate(-1, UintNField("u", 0, 3), IntNField("i", 3, 3)).Slice(make(Value, 12))
+				Expect(func() {
+					odd.SetColumn("u", []uint64{1, 0x1000000})
+				}).To(Panic())
+				Expect(func() {
+					odd.SetColumn("i", []int64{-0x800001, 0})
+				}).To(Panic())
+				Expect(odd.Value).To(Equal(make(Value, 12)))
+				odd.SetColumn("i", []int64{-0x800000, 0x7fffff})
+				Expect(odd.Column("i")).To(Equal([]int64{-0x800000, 0x7fffff}))
+			})
+		})
+		Describe("with floating point fields", func() {
+			It("shall return and set typed values", func() {
+				ss := NewTemplate(12,
+					Float32Field("f32", 0),
+					Float64Field("f64", 4),
+				).Slice(make(Value, 24))
+				ss.SetColumn("f32", []float32{1.5, -2})
+				ss.SetColumn("f64", []float64{0.25, 3})
+				Expect(ss.Column("f32")).To(Equal([]float32{1.5, -2}))
+				Expect(ss.Column("f64")).To(Equal([]float64{0.25, 3}))
+				Expect(ss.Nth(1).Lookup("f32").Float32()).To(Equal(float32(-2)))
+			})
+		})
+	})
 })
@@ -1,25 +1,38 @@
 package bmstruct
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"reflect"
+	"sort"
 )
 
 //Template is a set of fields and is similar to the struct data structure. The
 //Fields of a Template may overlap which results in a C union like data
 //structure.
 //
-//Besides the Fields, a Template specifies its size too.
+//Besides the Fields, a Template specifies its size too. The size of a Template
+//with dynamic fields (see DynamicField) is its minimum size.
+//
+//A Template created by NewTemplate remembers the declaration order of its
+//Fields. The order is used wherever the Fields are listed, e.g. by FieldNames,
+//Walk and the JSON representation.
 type Template struct {
 	Fields map[string]*Field `json:"fields"`
 	Size   int               `json:"size"`
+
+	names []string
+	order *fieldOrder
 }
 
 //NewTemplate creates a new Template object. It checks the validity of size and
 //whether the given fields fit into the given size. If the size parameter is
 //less than 0, NewTemplate will calculate the size based on the given fields.
 //
-//NewTemplate panics when the given size is too small or when no fields were
-//specified.
+//NewTemplate panics when the given size is too small, when no fields were
+//specified, when two fields have the same name, when the discriminator of a variant field is missing or when the
+//size field of a dynamic field is not an integer field preceding it.
 //
 //It is valid to specify a larger Template size than the fields require.
 func NewTemplate(size int, fields ...*Field) *Template {
@@ -31,20 +44,68 @@ func NewTemplate(size int, fields ...*Field) *Template {
 		Size:   size,
 	}
 	for _, field := range fields {
+		if _, found := t.Fields[field.Name]; found {
+			panic(fmt.Sprintf("duplicate field name %s", field.Name))
+		}
+		t.names = append(t.names, field.Name)
 		t.Fields[field.Name] = field
 	}
+	t.order = t.newFieldOrder()
 	if size < 0 {
 		t.Size = int(t.minLen())
-	} else if t.minLen() > uint64(size) {
-		panic("Template size too small")
+	}
+	if err := t.check(); err != nil {
+		panic(err.Error())
 	}
 	return t
 }
 
+// check returns an error if the Template is invalid, see NewTemplate.
+func (t *Template) check() error {
+	if len(t.Fields) == 0 {
+		return fmt.Errorf("at least 1 field shall be specified")
+	}
+	for _, field := range t.orderedFields() {
+		if field.Kind == VariantKind {
+			discriminator, found := t.Fields[field.Discriminator]
+			if !found {
+				return fmt.Errorf("discriminator %s of variant %s not found in template",
+					field.Discriminator, field.Name)
+			}
+			if !discriminator.isInteger() || discriminator.Len > 8 {
+				return fmt.Errorf("discriminator %s of variant %s shall be an integer field of at most 8 bytes",
+					field.Discriminator, field.Name)
+			}
+		}
+		for _, c := range field.Cases {
+			if c.Template == nil || c.Template.dynamic() {
+				return fmt.Errorf("template of case %s shall have a fixed size", c.Name)
+			}
+		}
+		if field.Template != nil && field.Template.dynamic() {
+			return fmt.Errorf("template with dynamic fields cannot be nested as %s", field.Name)
+		}
+		if field.Elem != nil && field.Elem.dynamic() {
+			return fmt.Errorf("element template of array %s shall have a fixed size", field.Name)
+		}
+	}
+	if err := t.checkDynamic(); err != nil {
+		return err
+	}
+	if t.Size < 0 || t.minLen() > uint64(t.Size) {
+		return fmt.Errorf("Template size too small")
+	}
+	return nil
+}
+
 func (t *Template) minLen() uint64 {
 	l := uint64(0)
 	for _, field := range t.Fields {
-		if fMax := field.Offset + field.Len; fMax > l {
+		fMax := field.Offset + field.Len
+		if field.PresentIf != "" {
+			fMax = field.Offset
+		}
+		if fMax > l {
 			l = fMax
 		}
 	}
@@ -57,27 +118,249 @@ func (t *Template) minLen() uint64 {
 //Note, that it returns true only when the order of the Fields is the same in
 //the other Template.
 func (t *Template) Equal(other *Template) bool {
-	return reflect.DeepEqual(t, other) || reflect.DeepEqual(*t, *other)
+	if t == other {
+		return true
+	}
+	if t.Size != other.Size || len(t.Fields) != len(other.Fields) {
+		return false
+	}
+	fields, otherFields := t.orderedFields(), other.orderedFields()
+	for i, field := range fields {
+		if field != otherFields[i] && !reflect.DeepEqual(field, otherFields[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+//FieldNames method returns the names of the Fields in declaration order.
+//
+//If the Template was not created by NewTemplate (or JSON unmarshaling) and thus
+//the declaration order is unknown, the Fields are ordered by their offset.
+func (t *Template) FieldNames() []string {
+	if t.declared() {
+		names := make([]string, len(t.names))
+		copy(names, t.names)
+		return names
+	}
+	names := make([]string, 0, len(t.Fields))
+	for name := range t.Fields {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		fi, fj := t.Fields[names[i]], t.Fields[names[j]]
+		if fi.Offset != fj.Offset {
+			return fi.Offset < fj.Offset
+		}
+		if fi.BitFieldOffset != fj.BitFieldOffset {
+			return fi.BitFieldOffset < fj.BitFieldOffset
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// declared returns true if the declaration order of the Fields is known, i.e.
+// the names remembered by NewTemplate are the names of the Fields.
+func (t *Template) declared() bool {
+	if len(t.names) != len(t.Fields) {
+		return false
+	}
+	for _, name := range t.names {
+		if _, found := t.Fields[name]; !found {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldOrder is the declaration order of the Fields of a Template and the
+// properties of the Template derived from it. It is computed once by
+// NewTemplate and UnmarshalJSON, so accessing the Fields does not need to scan
+// the Template.
+type fieldOrder struct {
+	//fields are the Fields in declaration order.
+	fields []*Field
+	//dynamic is true if a Field is dynamic.
+	dynamic bool
+	//shifted are the names of the Fields whose position depends on the data,
+	//i.e. a dynamic field is declared before them at the same or a smaller
+	//offset.
+	shifted map[string]bool
+	//dependents are the dynamic fields keyed by the name of their size field.
+	dependents map[string][]*Field
+}
+
+func (t *Template) newFieldOrder() *fieldOrder {
+	names := t.FieldNames()
+	o := &fieldOrder{
+		fields:     make([]*Field, len(names)),
+		shifted:    make(map[string]bool),
+		dependents: make(map[string][]*Field),
+	}
+	var dynamicFields []*Field
+	for i, name := range names {
+		field := t.Fields[name]
+		o.fields[i] = field
+		for _, f := range dynamicFields {
+			if f.Offset <= field.Offset {
+				o.shifted[name] = true
+				break
+			}
+		}
+		if field.dynamic() {
+			o.dynamic = true
+			dynamicFields = append(dynamicFields, field)
+			o.dependents[field.sizeField()] = append(o.dependents[field.sizeField()], field)
+		}
+	}
+	return o
+}
+
+// fieldOrder returns the fieldOrder of the Template. It is computed on every
+// call if the Template was not created by NewTemplate (or JSON unmarshaling)
+// or its Fields were added, removed or replaced since.
+func (t *Template) fieldOrder() *fieldOrder {
+	if t.order != nil && t.order.current(t) {
+		return t.order
+	}
+	return t.newFieldOrder()
+}
+
+// current returns true if the fieldOrder was computed from the current Fields of
+// the Template.
+func (o *fieldOrder) current(t *Template) bool {
+	if len(o.fields) != len(t.Fields) {
+		return false
+	}
+	for _, field := range o.fields {
+		if t.Fields[field.Name] != field {
+			return false
+		}
+	}
+	return true
+}
+
+// orderedFields returns the Fields in declaration order. The returned slice
+// shall not be modified.
+func (t *Template) orderedFields() []*Field {
+	return t.fieldOrder().fields
+}
+
+//WalkFn is the type of the function called by Walk for each Field. The path is
+//the name of the Field prefixed with the names of the enclosing Fields
+//separated by dots, e.g. "grades.math", and offset is the offset of the Field
+//from the beginning of the outermost Template.
+type WalkFn func(path string, offset uint64, field *Field)
+
+//Walk method calls fn for each Field of the Template in declaration order. For
+//a Field that was created from another Template with the Field method, fn is
+//called for the Field first and then for the Fields of the nested Template.
+//Similarly, the Fields of every Case of a variant Field are walked with the
+//path prefix "variant.case.".
+func (t *Template) Walk(fn WalkFn) {
+	t.walk("", 0, fn)
+}
+
+func (t *Template) walk(prefix string, base uint64, fn WalkFn) {
+	for _, field := range t.orderedFields() {
+		path := prefix + field.Name
+		fn(path, base+field.Offset, field)
+		if field.Template != nil {
+			field.Template.walk(path+".", base+field.Offset, fn)
+		}
+		for _, c := range field.Cases {
+			c.Template.walk(path+"."+c.Name+".", base+field.Offset, fn)
+		}
+	}
 }
 
 //Field method turns the Template object into a Field object. This can be used
 //to define hierarchical templates, i.e. a Template that contains another
 //Template.
+//
+//Field panics if the Template has dynamic fields.
 func (t *Template) Field(name string, offset uint64) *Field {
+	if t.dynamic() {
+		panic(fmt.Sprintf("template with dynamic fields cannot be nested as %s", name))
+	}
 	return &Field{
-		Name:   name,
-		Offset: offset,
-		Len:    uint64(t.Size),
+		Name:     name,
+		Offset:   offset,
+		Len:      uint64(t.Size),
+		Template: t,
 	}
 }
 
-//FieldAt method returns the Field that is to be found at the given offset. The
+//FieldAt method returns the Field that is to be found at the given offset. If
+//more Fields start at the offset, the one declared first is returned. The
 //method panics if the offset is invalid.
 func (t *Template) FieldAt(offset uint64) *Field {
-	for _, f := range t.Fields {
+	for _, f := range t.orderedFields() {
 		if f.Offset == offset {
 			return f
 		}
 	}
 	panic("invalid offset")
 }
+
+// lookupField returns the Field with the given name. It panics if the Template
+// has no such Field.
+func (t *Template) lookupField(fieldName string) *Field {
+	field, found := t.Fields[fieldName]
+	if !found {
+		panic(fmt.Sprintf("field name %s not found in template", fieldName))
+	}
+	return field
+}
+
+type templateJSON struct {
+	Fields []*Field `json:"fields"`
+	Size   int      `json:"size"`
+}
+
+//MarshalJSON implements the json.Marshaler interface. The Fields are listed in
+//declaration order.
+func (t *Template) MarshalJSON() ([]byte, error) {
+	return json.Marshal(templateJSON{
+		Fields: t.orderedFields(),
+		Size:   t.Size,
+	})
+}
+
+//UnmarshalJSON implements the json.Unmarshaler interface. Besides the list of
+//Fields written by MarshalJSON, it accepts the Fields as a JSON object keyed by
+//the Field names. UnmarshalJSON returns an error for a Template that
+//NewTemplate would panic for.
+func (t *Template) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Fields json.RawMessage `json:"fields"`
+		Size   int             `json:"size"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	var fields []*Field
+	if trimmed := bytes.TrimSpace(raw.Fields); len(trimmed) > 0 && trimmed[0] == '{' {
+		var fieldMap map[string]*Field
+		if err := json.Unmarshal(raw.Fields, &fieldMap); err != nil {
+			return err
+		}
+		legacy := &Template{Fields: fieldMap}
+		fields = legacy.orderedFields()
+	} else if err := json.Unmarshal(raw.Fields, &fields); err != nil {
+		return err
+	}
+	t.Fields = make(map[string]*Field)
+	t.names = nil
+	t.Size = raw.Size
+	for _, field := range fields {
+		if _, found := t.Fields[field.Name]; found {
+			return fmt.Errorf("duplicate field name %s", field.Name)
+		}
+		t.names = append(t.names, field.Name)
+		t.Fields[field.Name] = field
+	}
+	t.order = t.newFieldOrder()
+	return t.check()
+}
@@ -1,6 +1,8 @@
 package bmstruct
 
 import (
+	"encoding/json"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -81,6 +83,155 @@ var _ = Describe("Template", func() {
 			})
 		})
 	})
+	Describe("Order of the fields", func() {
+		var t *Template
+
+		BeforeEach(func() {
+			grades := NewTemplate(-1,
+				Uint8Field("science", 1),
+				Uint8Field("math", 0),
+			)
+			t = NewTemplate(40,
+				IntField("id", 8),
+				grades.Field("grades", 16),
+				IntField("age", 0),
+				Uint8Field("tag", 8),
+			)
+		})
+		It("should be the declaration order", func() {
+			Expect(t.FieldNames()).To(Equal([]string{"id", "grades", "age", "tag"}))
+		})
+		It("should be the offset order for templates without declaration order", func() {
+			t2 := &Template{Fields: t.Fields, Size: t.Size}
+			Expect(t2.FieldNames()).To(Equal([]string{"age", "id", "tag", "grades"}))
+		})
+		It("should be used by Walk including the nested templates", func() {
+			paths := []string{}
+			offsets := []uint64{}
+			t.Walk(func(path string, offset uint64, field *Field) {
+				paths = append(paths, path)
+				offsets = append(offsets, offset)
+			})
+			Expect(paths).To(Equal([]string{
+				"id", "grades", "grades.science", "grades.math", "age", "tag",
+			}))
+			Expect(offsets).To(Equal([]uint64{8, 16, 17, 16, 0, 8}))
+		})
+		It("should be used by FieldAt for overlapping fields", func() {
+			for i := 0; i < 10; i++ {
+				Expect(t.FieldAt(8).Name).To(Equal("id"))
+			}
+		})
+		It("should be compared by Equal", func() {
+			t1 := NewTemplate(16, IntField("f1", 0), IntField("f2", 8))
+			t2 := NewTemplate(16, IntField("f2", 8), IntField("f1", 0))
+			Expect(t1.Equal(t2)).To(BeFalse())
+		})
+		It("should be preserved through JSON", func() {
+			b, err := json.Marshal(t)
+			Expect(err).NotTo(HaveOccurred())
+			var t2 Template
+			Expect(json.Unmarshal(b, &t2)).To(Succeed())
+			Expect(t2.FieldNames()).To(Equal(t.FieldNames()))
+			Expect(t2.Equal(t)).To(BeTrue())
+			Expect(t2.Fields["grades"].Template.FieldNames()).To(Equal([]string{"science", "math"}))
+		})
+		It("should list the fields in JSON in declaration order", func() {
+			b, err := json.Marshal(NewTemplate(-1, Uint8Field("b", 1), Uint8Field("a", 0)))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(b)).To(Equal(`{"fields":[` +
+				`{"name":"b","offset":1,"length":1,"kind":"uint8"},` +
+				`{"name":"a","offset":0,"length":1,"kind":"uint8"}],"size":2}`))
+		})
+		It("should accept fields as a JSON object", func() {
+			var t2 Template
+			Expect(json.Unmarshal([]byte(`{"fields":{`+
+				`"b":{"name":"b","offset":1,"length":1},`+
+				`"a":{"name":"a","offset":0,"length":1}},"size":2}`), &t2)).To(Succeed())
+			Expect(t2.FieldNames()).To(Equal([]string{"a", "b"}))
+			Expect(t2.Size).To(Equal(2))
+		})
+		It("should reject duplicate fields in JSON", func() {
+			var t2 Template
+			Expect(json.Unmarshal([]byte(`{"fields":[`+
+				`{"name":"a","offset":1,"length":1},`+
+				`{"name":"a","offset":0,"length":1}],"size":2}`), &t2)).NotTo(Succeed())
+			Expect(func() {
+				NewTemplate(2, Uint8Field("a", 1), Uint8Field("a", 0))
+			}).To(Panic())
+		})
+		It("should follow the fields replaced after NewTemplate", func() {
+			t2 := NewTemplate(-1, Uint8Field("len", 0), Uint8Field("body", 1), Uint8Field("tail", 1))
+			t2.Fields["body"] = DynamicField("body", 1, "len")
+			s := t2.New(Value{2, 'h', 'i', 9})
+			Expect(s.Lookup("body")).To(Equal(Value("hi")))
+			Expect(s.Lookup("tail")).To(Equal(Value{9}))
+			t2.Fields["extra"] = Uint8Field("extra", 2)
+			delete(t2.Fields, "tail")
+			Expect(t2.FieldNames()).To(Equal([]string{"len", "body", "extra"}))
+		})
+		It("should reject invalid templates in JSON", func() {
+			for _, s := range []string{
+				`{"fields":[],"size":0}`,
+				`{"fields":[{"name":"a","offset":0,"length":4}],"size":2}`,
+				`{"fields":[{"name":"data","offset":0,"length":0,"len-field":"nope"}],"size":1}`,
+				`{"fields":[{"name":"data","offset":0,"length":0,"len-field":"len"},` +
+					`{"name":"len","offset":0,"length":1}],"size":1}`,
+				`{"fields":[{"name":"v","offset":0,"length":0,"kind":"variant","discriminator":"t"}],"size":0}`,
+			} {
+				var t2 Template
+				Expect(json.Unmarshal([]byte(s), &t2)).NotTo(Succeed(), s)
+			}
+		})
+	})
+	Describe("Field options in JSON", func() {
+		It("should be preserved for each kind of field", func() {
+			strict := BoolField("strict", 1)
+			strict.Strict = true
+			label := StringField("label", 0, 6, ' ')
+			label.Truncate = true
+			label.Charset = "ebcdic"
+			size := NumericTextField("size", 0, 12, 8)
+			size.Terminator = "\x00"
+			size.Padding = ' '
+			addr := Uint128Field("addr", 0)
+			addr.BigEndian = true
+			point := NewTemplate(-1, Uint8Field("x", 0), Uint8Field("y", 1))
+			for _, t := range []*Template{
+				NewTemplate(-1, Int16Field("n", 0), UintNField("u", 2, 3), BitField("b", 5, 2, 3)),
+				NewTemplate(-1, point.Field("point", 0)),
+				NewTemplate(-1, EnumField(Uint16Field("e", 0), NewEnum(map[string]uint64{"A": 1, "B": 2}))),
+				NewTemplate(-1, FlagsField("f", 0, 2, NewFlagSet(map[string]uint8{"A": 0, "B": 9}))),
+				NewTemplate(-1, BoolBitField("bit", 0, 3), strict),
+				NewTemplate(-1, label, UTF16StringField("wide", 6, 8, true), PascalStringField("p", 14, 2, 4)),
+				NewTemplate(-1, Uint8Field("len", 0), DynamicStringField("s", 1, "len")),
+				NewTemplate(-1, size),
+				NewTemplate(-1, ZonedDecimalField("z", 0, 5, 2, true), PackedDecimalField("p", 5, 7, 0, false)),
+				NewTemplate(-1, addr, BigUintField("u", 16, 32), BigIntField("i", 48, 20)),
+				NewTemplate(-1,
+					Uint8Field("len", 0),
+					Uint8Field("count", 1),
+					DynamicField("data", 2, "len"),
+					ArrayField("points", 2, "count", point),
+				),
+				NewTemplate(-1, BitField("p", 0, 0, 1), OptionalField(Uint32Field("o", 1), "p")),
+				NewTemplate(-1,
+					Uint8Field("type", 0),
+					VariantField("body", 1, "type",
+						&Case{Name: "a", Value: 1, Template: point},
+						&Case{Name: "b", Value: 2, Template: NewTemplate(-1, Uint32Field("v", 0))},
+					),
+				),
+			} {
+				b, err := json.Marshal(t)
+				Expect(err).NotTo(HaveOccurred())
+				var decoded Template
+				Expect(json.Unmarshal(b, &decoded)).To(Succeed(), string(b))
+				Expect(decoded.Equal(t)).To(BeTrue(), string(b))
+				Expect(decoded.Size).To(Equal(t.Size), string(b))
+			}
+		})
+	})
 	Describe("Getting the field at offset", func() {
 		var t *Template
 
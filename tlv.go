@@ -0,0 +1,232 @@
+package bmstruct
+
+import (
+	"fmt"
+	"io"
+)
+
+//TLVFormat describes a stream of type-length-value records like netlink
+//attributes or DHCP options. Every record starts with a header described by
+//Header, which contains the type and the length of the record in the integer
+//fields named by TypeField and LenField. The header is followed by the body,
+//which is interpreted by the Template registered for the type in Bodies.
+type TLVFormat struct {
+	Header    *Template
+	TypeField string
+	LenField  string
+	//HeaderInLen tells that the length field includes the size of the header
+	//too, e.g. in netlink attributes. Otherwise it is the length of the body.
+	HeaderInLen bool
+	//Align is the alignment of the records. The records are padded with zeros
+	//so that each of them starts at a multiple of Align. Align 0 and 1 mean no
+	//padding.
+	Align int
+	//Bodies are the Templates of the bodies by type. The body of a type without
+	//a Template is available as a Value only.
+	Bodies map[uint64]*Template
+}
+
+// fields returns the type and the length fields of the header. It panics if
+// they do not exist or are not integer fields of fixed position.
+func (f *TLVFormat) fields() (typeField, lenField *Field) {
+	typeField = f.Header.fixedField(f.TypeField)
+	lenField = f.Header.fixedField(f.LenField)
+	if !typeField.isInteger() || !lenField.isInteger() {
+		panic(fmt.Sprintf("type field %s and length field %s shall be integers",
+			f.TypeField, f.LenField))
+	}
+	return typeField, lenField
+}
+
+// padding returns the number of padding bytes following a record that ends at
+// offset.
+func (f *TLVFormat) padding(offset int) int {
+	if f.Align <= 1 {
+		return 0
+	}
+	return (f.Align - offset%f.Align) % f.Align
+}
+
+//TLV is a single record of a TLV stream.
+type TLV struct {
+	//Type is the value of the type field of the header.
+	Type uint64
+	//Header is the header of the record.
+	Header *Struct
+	//Body is the body of the record mapped to the Template registered for Type
+	//or nil if no Template is registered for it.
+	Body *Struct
+	//Data is the body of the record without padding.
+	Data Value
+}
+
+//TLVReader iterates over the TLV records of a Value.
+type TLVReader struct {
+	format    *TLVFormat
+	typeField *Field
+	lenField  *Field
+	data      Value
+	offset    int
+}
+
+//NewTLVReader creates a new TLVReader that reads the records of the given
+//format from data.
+//
+//NewTLVReader panics if the type or the length field is missing from the
+//header Template or is not an integer.
+func NewTLVReader(data Valuable, format *TLVFormat) *TLVReader {
+	typeField, lenField := format.fields()
+	return &TLVReader{
+		format:    format,
+		typeField: typeField,
+		lenField:  lenField,
+		data:      data.GetValue(),
+	}
+}
+
+//Offset method returns the offset of the next record in the data.
+func (r *TLVReader) Offset() int {
+	return r.offset
+}
+
+//Next method returns the next record. The Structs of the returned TLV refer to
+//the data of the TLVReader, so modifying them modifies the data.
+//
+//Next returns io.EOF when there are no more records and a *PartialRecordError
+//when the data ends in the middle of a record. It returns an error if the
+//length field is invalid or the body is too short for the registered Template.
+func (r *TLVReader) Next() (*TLV, error) {
+	rest := r.data[r.offset:]
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	headerSize := r.format.Header.Size
+	if len(rest) < headerSize {
+		return nil, &PartialRecordError{
+			Offset: int64(r.offset),
+			Len:    len(rest),
+			Size:   headerSize,
+		}
+	}
+	header := rest[:headerSize]
+	length := r.lenField.uintOf(header)
+	if r.format.HeaderInLen {
+		if length < uint64(headerSize) {
+			return nil, fmt.Errorf("invalid length %d of record at offset %d",
+				length, r.offset)
+		}
+		length -= uint64(headerSize)
+	}
+	if length > uint64(len(rest)-headerSize) {
+		return nil, &PartialRecordError{
+			Offset: int64(r.offset),
+			Len:    len(rest),
+			Size:   headerSize + int(length),
+		}
+	}
+	size := headerSize + int(length)
+	tlv := &TLV{
+		Type:   r.typeField.uintOf(header),
+		Header: r.format.Header.New(header),
+		Data:   rest[headerSize:size],
+	}
+	if body, found := r.format.Bodies[tlv.Type]; found {
+		s, _, err := body.Parse(tlv.Data)
+		if err != nil {
+			return nil, fmt.Errorf("body of record at offset %d: %w", r.offset, err)
+		}
+		tlv.Body = s
+	}
+	size += r.format.padding(r.offset + size)
+	if size > len(rest) {
+		size = len(rest)
+	}
+	r.offset += size
+	return tlv, nil
+}
+
+//Iter method calls fn for each record until the end of the data. It returns
+//nil at the end of the data, otherwise the error of Next.
+func (r *TLVReader) Iter(fn func(tlv *TLV)) error {
+	for {
+		tlv, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fn(tlv)
+	}
+}
+
+//TLVBuilder builds a stream of TLV records.
+type TLVBuilder struct {
+	format    *TLVFormat
+	typeField *Field
+	lenField  *Field
+	value     Value
+}
+
+//NewTLVBuilder creates a new TLVBuilder that builds records of the given
+//format.
+//
+//NewTLVBuilder panics if the type or the length field is missing from the
+//header Template or is not an integer.
+func NewTLVBuilder(format *TLVFormat) *TLVBuilder {
+	typeField, lenField := format.fields()
+	return &TLVBuilder{
+		format:    format,
+		typeField: typeField,
+		lenField:  lenField,
+	}
+}
+
+//Append method appends a record with the given type and body. The header is
+//zeroed out except for the type and the length field. The record is followed
+//by the padding required by the alignment of the format.
+//
+//Append panics if the type or the length does not fit into its field.
+func (b *TLVBuilder) Append(typ uint64, body Valuable) {
+	b.AppendHeader(b.format.Header.Empty(), typ, body)
+}
+
+//AppendHeader method works like Append, but the header of the record is the
+//copy of the given header with the type and the length fields set.
+//
+//AppendHeader panics if the Template of the header differs from the header
+//Template of the format.
+func (b *TLVBuilder) AppendHeader(header *Struct, typ uint64, body Valuable) {
+	if !b.format.Header.Equal(header.Template) {
+		panic("header with different kind of template cannot be appended")
+	}
+	data := body.GetValue()
+	length := uint64(len(data))
+	if b.format.HeaderInLen {
+		length += uint64(b.format.Header.Size)
+	}
+	checkFits(b.typeField, typ)
+	checkFits(b.lenField, length)
+	start := len(b.value)
+	b.value = append(b.value, header.Value...)
+	b.typeField.putUint(b.value[start:], typ)
+	b.lenField.putUint(b.value[start:], length)
+	b.value = append(b.value, data...)
+	b.value = append(b.value, make(Value, b.format.padding(len(b.value)))...)
+}
+
+//Len method returns the length of the records built so far.
+func (b *TLVBuilder) Len() int {
+	return len(b.value)
+}
+
+//Value method returns the records built so far. The returned Value refers to
+//the buffer of the TLVBuilder until the next call of Append.
+func (b *TLVBuilder) Value() Value {
+	return b.value
+}
+
+//Reset method removes all records from the TLVBuilder.
+func (b *TLVBuilder) Reset() {
+	b.value = b.value[:0]
+}
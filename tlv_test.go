@@ -0,0 +1,129 @@
+package bmstruct
+
+import (
+	"errors"
+	"io"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TLV", func() {
+	var netlink, dhcp *TLVFormat
+	var mtu, addr *Template
+
+	BeforeEach(func() {
+		mtu = NewTemplate(-1, Uint32Field("mtu", 0))
+		addr = NewTemplate(-1, (&Template{Size: 4}).Field("ip", 0))
+		netlink = &TLVFormat{
+			Header: NewTemplate(-1,
+				Uint16Field("len", 0),
+				Uint16Field("type", 2),
+			),
+			TypeField:   "type",
+			LenField:    "len",
+			HeaderInLen: true,
+			Align:       4,
+			Bodies: map[uint64]*Template{
+				4: mtu,
+			},
+		}
+		dhcp = &TLVFormat{
+			Header: NewTemplate(-1,
+				Uint8Field("code", 0),
+				Uint8Field("len", 1),
+			),
+			TypeField: "code",
+			LenField:  "len",
+			Bodies: map[uint64]*Template{
+				50: addr,
+			},
+		}
+	})
+	Describe("building records", func() {
+		It("should set the length fields and align the records", func() {
+			b := NewTLVBuilder(netlink)
+			b.Append(3, Value("eth0\x00"))
+			b.Append(4, Uint32(1500))
+			Expect(b.Value()).To(Equal(Value{
+				9, 0, 3, 0, 'e', 't', 'h', '0', 0, 0, 0, 0,
+				8, 0, 4, 0, 0xdc, 0x05, 0, 0,
+			}))
+			Expect(b.Len()).To(Equal(20))
+			b.Reset()
+			Expect(b.Len()).To(Equal(0))
+		})
+		It("should keep the other fields of the header", func() {
+			header := NewTemplate(-1,
+				Uint8Field("type", 0),
+				BitField("flag", 1, 7, 1),
+				BitField("len", 1, 0, 7),
+			)
+			format := &TLVFormat{Header: header, TypeField: "type", LenField: "len"}
+			h := header.Empty()
+			h.Update("flag", Value{1})
+			b := NewTLVBuilder(format)
+			b.AppendHeader(h, 2, Value{0xaa, 0xbb})
+			Expect(b.Value()).To(Equal(Value{2, 0x82, 0xaa, 0xbb}))
+			Expect(func() {
+				b.Append(2, make(Value, 128))
+			}).To(Panic())
+			Expect(func() {
+				b.Append(256, Value{})
+			}).To(Panic())
+		})
+	})
+	Describe("reading records", func() {
+		It("should yield the records with typed bodies", func() {
+			b := NewTLVBuilder(netlink)
+			b.Append(3, Value("eth0\x00"))
+			b.Append(4, Uint32(1500))
+			r := NewTLVReader(b.Value(), netlink)
+			tlv, err := r.Next()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tlv.Type).To(Equal(uint64(3)))
+			Expect(tlv.Data).To(Equal(Value("eth0\x00")))
+			Expect(tlv.Body).To(BeNil())
+			Expect(r.Offset()).To(Equal(12))
+			tlv, err = r.Next()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tlv.Header.Lookup("len").Uint16()).To(Equal(uint16(8)))
+			Expect(tlv.Body.Lookup("mtu").Uint32()).To(Equal(uint32(1500)))
+			_, err = r.Next()
+			Expect(err).To(Equal(io.EOF))
+		})
+		It("should iterate over unpadded records", func() {
+			data := Value{53, 1, 5, 50, 4, 192, 168, 0, 1}
+			types := []uint64{}
+			err := NewTLVReader(data, dhcp).Iter(func(tlv *TLV) {
+				types = append(types, tlv.Type)
+				if tlv.Body != nil {
+					Expect(tlv.Body.Lookup("ip")).To(Equal(Value{192, 168, 0, 1}))
+				}
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(types).To(Equal([]uint64{53, 50}))
+		})
+		It("should report invalid records", func() {
+			var partial *PartialRecordError
+			_, err := NewTLVReader(Value{53, 2, 5}, dhcp).Next()
+			Expect(errors.As(err, &partial)).To(BeTrue())
+			Expect(*partial).To(Equal(PartialRecordError{Len: 3, Size: 4}))
+			_, err = NewTLVReader(Value{53}, dhcp).Next()
+			Expect(errors.As(err, &partial)).To(BeTrue())
+			_, err = NewTLVReader(Value{50, 1, 192}, dhcp).Next()
+			Expect(err).To(HaveOccurred())
+			_, err = NewTLVReader(Value{2, 0, 1, 0}, netlink).Next()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	It("should panic for invalid formats", func() {
+		dhcp.LenField = "length"
+		Expect(func() {
+			NewTLVReader(Value{}, dhcp)
+		}).To(Panic())
+		Expect(func() {
+			NewTLVBuilder(dhcp)
+		}).To(Panic())
+	})
+})
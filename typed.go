@@ -0,0 +1,499 @@
+package bmstruct
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+//ErrOverflow is returned (wrapped) by Set when the given value does not fit
+//into the field.
+var ErrOverflow = errors.New("value overflows field")
+
+//Get method returns the field indicated by fieldName converted to the Go type
+//that matches the Kind of the field:
+//
+//  integer kinds     the integer type of the same name, e.g. uint16
+//  bit fields        uint8
+//  BoolKind          bool
+//  floating point    float32 or float64
+//  StringKind        string
+//  decimal kinds     Decimal
+//  NumericTextKind   uint64
+//  Uint128Kind       Uint128
+//  big integers      *big.Int
+//  other kinds       Value (a copy, like Lookup returns)
+//
+//The fieldName may be a path as described at the Lookup method. Get returns
+//nil for an absent optional field.
+//
+//Get does not validate the content of the fields: a decimal or numeric text
+//field that does not contain a valid number, e.g. one filled with spaces, and a
+//strict bool field that does not contain 0 or 1 are returned as a Value. The
+//Decimal, Number and BoolE methods return an error for such fields instead.
+//
+//Get panics for a non-existing field name.
+func (s *Struct) Get(fieldName string) interface{} {
+	field := s.resolvePresent(fieldName)
+	if field == nil {
+		return nil
+	}
+	return field.get(s.Value)
+}
+
+//Set method changes the field indicated by fieldName to v. The type of v shall
+//be one that Get returns for the field, except that integer fields accept any
+//Go integer type and floating point fields accept float32 and float64. Decimal
+//fields accept a Decimal, a string parsed by ParseDecimal and any Go integer
+//type, numeric text fields accept any Go integer type. Enum fields accept the
+//names of their values too, see UpdateName, and flags fields accept the names
+//of their bits joined by '|', see FlagsField. Uint128 and big integer fields
+//accept a Uint128, a *big.Int and any Go integer type. Fields returned as Value
+//by Get accept any Valuable.
+//
+//Like Update, Set inserts absent optional fields and resizes dynamic fields.
+//
+//Set returns an error if v has an incorrect type or size and an error wrapping
+//ErrOverflow if v does not fit into the field. Set panics for a non-existing
+//field name.
+func (s *Struct) Set(fieldName string, v interface{}) error {
+	field := s.resolvePresent(fieldName)
+	original, topLevel := s.Template.Fields[fieldName]
+	if field == nil {
+		if !topLevel {
+			return fmt.Errorf("field %s: %w", fieldName, ErrFieldAbsent)
+		}
+		field = original
+	}
+	value, err := field.encode(v)
+	if err != nil {
+		return fmt.Errorf("field %s: %w", fieldName, err)
+	}
+	if topLevel && original.dynamic() && original.PresentIf == "" {
+		sizeField := s.resolve(original.sizeField()).field
+		if n := uint64(len(value)) / original.unitLen(); !fits(sizeField, n) {
+			return fmt.Errorf("field %s: size %d does not fit into field %s: %w",
+				fieldName, n, sizeField.Name, ErrOverflow)
+		}
+	} else if field.BitFieldLen == 0 && uint64(len(value)) != field.Len {
+		return fmt.Errorf("field %s: value size (%d bytes) and field length (%d bytes) mismatch",
+			fieldName, len(value), field.Len)
+	}
+	s.Update(fieldName, value)
+	return nil
+}
+
+// get returns the field from data converted to the Go type matching its Kind.
+func (f *Field) get(data []byte) interface{} {
+	if f.Kind == BoolKind {
+		b, err := f.boolOf(data)
+		if err != nil {
+			return f.copySlice(data)
+		}
+		return b
+	}
+	if f.BitFieldLen != 0 {
+		return uint8(f.uintOf(data))
+	}
+	switch f.Kind {
+	case Uint8Kind:
+		return uint8(f.uintOf(data))
+	case Int8Kind:
+		return int8(f.intOf(data))
+	case Uint16Kind:
+		return uint16(f.uintOf(data))
+	case Int16Kind:
+		return int16(f.intOf(data))
+	case Uint32Kind:
+		return uint32(f.uintOf(data))
+	case Int32Kind:
+		return int32(f.intOf(data))
+	case Uint64Kind:
+		return f.uintOf(data)
+	case Int64Kind:
+		return f.intOf(data)
+	case UintKind:
+		return uint(f.uintOf(data))
+	case IntKind:
+		return int(f.intOf(data))
+	case UintptrKind:
+		return uintptr(f.uintOf(data))
+	case Float32Kind:
+		return float32(f.floatOf(data))
+	case Float64Kind:
+		return f.floatOf(data)
+	case StringKind:
+		return f.stringOf(data)
+	case ZonedDecimalKind, PackedDecimalKind:
+		d, err := f.decimalOf(data)
+		if err != nil {
+			return f.copySlice(data)
+		}
+		return d
+	case Uint128Kind:
+		return f.uint128Of(data)
+	case BigUintKind, BigIntKind:
+		return f.bigOf(data)
+	case NumericTextKind:
+		n, err := f.numberOf(data)
+		if err != nil {
+			return f.copySlice(data)
+		}
+		return n
+	default:
+		return f.copySlice(data)
+	}
+}
+
+//Decimal method returns the decimal field indicated by fieldName. Unlike Get,
+//it returns an error if the field does not contain a valid number, e.g. it is
+//filled with spaces. It returns an error wrapping ErrFieldAbsent for an absent
+//optional field and an error for a field that is not a decimal.
+//
+//Decimal panics for a non-existing field name.
+func (s *Struct) Decimal(fieldName string) (Decimal, error) {
+	field := s.resolvePresent(fieldName)
+	switch {
+	case field == nil:
+		return Decimal{}, fmt.Errorf("field %s: %w", fieldName, ErrFieldAbsent)
+	case field.Kind != ZonedDecimalKind && field.Kind != PackedDecimalKind:
+		return Decimal{}, fmt.Errorf("field %s of kind %s is not a decimal",
+			fieldName, field.Kind)
+	}
+	d, err := field.decimalOf(s.Value)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("field %s: %w", fieldName, err)
+	}
+	return d, nil
+}
+
+//Number method returns the numeric text field indicated by fieldName. Unlike
+//Get, it returns an error if the field does not contain a valid number. It
+//returns an error wrapping ErrFieldAbsent for an absent optional field and an
+//error for a field that is not a numeric text.
+//
+//Number panics for a non-existing field name.
+func (s *Struct) Number(fieldName string) (uint64, error) {
+	field := s.resolvePresent(fieldName)
+	switch {
+	case field == nil:
+		return 0, fmt.Errorf("field %s: %w", fieldName, ErrFieldAbsent)
+	case field.Kind != NumericTextKind:
+		return 0, fmt.Errorf("field %s of kind %s is not a numeric text",
+			fieldName, field.Kind)
+	}
+	n, err := field.numberOf(s.Value)
+	if err != nil {
+		return 0, fmt.Errorf("field %s: %w", fieldName, err)
+	}
+	return n, nil
+}
+
+// encode converts v to the Value of the field. The returned error does not
+// contain the name of the field.
+func (f *Field) encode(v interface{}) (Value, error) {
+	switch {
+	case f.Kind == BoolKind:
+		if x, ok := v.(bool); ok {
+			return Bool(x), nil
+		}
+	case f.BitFieldLen != 0 || f.Kind.Integer():
+		if name, ok := v.(string); ok && f.Enum != nil {
+			return f.encodeName(name)
+		}
+		if names, ok := v.(string); ok && f.Flags != nil {
+			return f.encodeFlags(names)
+		}
+		return f.encodeInteger(v)
+	case f.Kind == Float32Kind:
+		switch x := v.(type) {
+		case float32:
+			return f.uintValue(uint64(math.Float32bits(x))), nil
+		case float64:
+			if !math.IsInf(x, 0) && math.Abs(x) > math.MaxFloat32 {
+				return nil, fmt.Errorf("%g: %w", x, ErrOverflow)
+			}
+			return f.uintValue(uint64(math.Float32bits(float32(x)))), nil
+		}
+	case f.Kind == Float64Kind:
+		switch x := v.(type) {
+		case float32:
+			return f.uintValue(math.Float64bits(float64(x))), nil
+		case float64:
+			return f.uintValue(math.Float64bits(x)), nil
+		}
+	case f.Kind == StringKind:
+		if x, ok := v.(string); ok {
+			return f.encodeString(x)
+		}
+	case f.Kind == ZonedDecimalKind || f.Kind == PackedDecimalKind:
+		return f.encodeDecimal(v)
+	case f.Kind == NumericTextKind:
+		return f.encodeNumber(v)
+	case f.Kind == Uint128Kind || f.Kind == BigUintKind || f.Kind == BigIntKind:
+		return f.encodeBig(v)
+	default:
+		if x, ok := v.(Valuable); ok {
+			return x.GetValue().Clone(), nil
+		}
+	}
+	return nil, fmt.Errorf("value of type %T cannot be stored in a field of kind %s",
+		v, f.Kind)
+}
+
+// marshalFields returns the JSON object of the present fields of the Struct in
+// declaration order, see Struct.MarshalJSON.
+func (s *Struct) marshalFields() (json.RawMessage, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	if !s.Template.dynamic() && len(s.Value) < s.Template.Size {
+		return nil, fmt.Errorf("data (%d bytes) is shorter than the record (%d bytes)",
+			len(s.Value), s.Template.Size)
+	}
+	for _, name := range s.Template.FieldNames() {
+		r, err := s.Template.resolve(s.Value, name)
+		if errors.Is(err, ErrFieldAbsent) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := r.field
+		v, err := field.jsonOf(s.Value)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", name, err)
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", name, err)
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(b)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonOf returns the field from data converted to a value that encoding/json
+// represents as described at Struct.MarshalJSON.
+func (f *Field) jsonOf(data []byte) (interface{}, error) {
+	switch {
+	case f.Enum != nil:
+		u := f.uintOf(data)
+		if name, found := f.Enum.Name(u); found {
+			return name, nil
+		}
+		return u, nil
+	case f.Flags != nil:
+		return f.Flags.Format(f.uintOf(data)), nil
+	case f.Kind == BytesKind && f.Template != nil:
+		nested := &Struct{Template: f.Template, Value: f.slice(data)}
+		return nested.marshalFields()
+	case f.Kind == StringKind:
+		if _, err := lookupCharset(f.Charset); err != nil {
+			return nil, err
+		}
+	}
+	v := f.get(data)
+	switch x := v.(type) {
+	case Value:
+		if f.Kind != BytesKind && f.Kind != VariantKind {
+			return nil, nil
+		}
+	case Decimal:
+		return x.String(), nil
+	case *big.Int:
+		return x.String(), nil
+	case float32:
+		return jsonFloat(float64(x)), nil
+	case float64:
+		return jsonFloat(x), nil
+	}
+	return v, nil
+}
+
+// jsonFloat returns f or the string representation of f if it is not finite,
+// since JSON has no numbers for NaN and infinities.
+func jsonFloat(f float64) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return f
+}
+
+// unmarshalFields sets the fields of the Struct from the JSON object returned
+// by marshalFields, see Struct.UnmarshalJSON.
+func (s *Struct) unmarshalFields(b json.RawMessage) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	for name := range fields {
+		if _, found := s.Template.Fields[name]; !found {
+			return fmt.Errorf("field %s does not exist", name)
+		}
+	}
+	for _, name := range s.Template.FieldNames() {
+		raw, found := fields[name]
+		if !found || bytes.Equal(raw, []byte("null")) {
+			continue
+		}
+		field := s.Template.Fields[name]
+		if field.Kind == BytesKind && field.Template != nil {
+			nested := &Struct{Template: field.Template, Value: make(Value, field.Len)}
+			if current := s.resolvePresent(name); current != nil {
+				nested.Value = current.copySlice(s.Value)
+			}
+			if err := nested.unmarshalFields(raw); err != nil {
+				return fmt.Errorf("field %s: %w", name, err)
+			}
+			if err := s.Set(name, nested.Value); err != nil {
+				return err
+			}
+			continue
+		}
+		v, err := field.fromJSON(raw)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+		if err := s.Set(name, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fromJSON converts the JSON representation of the field returned by jsonOf to
+// a value that Set accepts.
+func (f *Field) fromJSON(b json.RawMessage) (interface{}, error) {
+	if f.Kind == BytesKind || f.Kind == VariantKind {
+		var v Value
+		err := json.Unmarshal(b, &v)
+		return v, err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	switch x := v.(type) {
+	case json.Number:
+		switch f.Kind {
+		case Float32Kind, Float64Kind:
+			return x.Float64()
+		case ZonedDecimalKind, PackedDecimalKind:
+			return x.String(), nil
+		case Uint128Kind, BigUintKind, BigIntKind:
+			return parseBig(x.String())
+		}
+		if i, err := x.Int64(); err == nil {
+			return i, nil
+		}
+		return strconv.ParseUint(x.String(), 10, 64)
+	case string:
+		switch f.Kind {
+		case Float32Kind, Float64Kind:
+			return strconv.ParseFloat(x, 64)
+		case Uint128Kind, BigUintKind, BigIntKind:
+			return parseBig(x)
+		}
+	}
+	return v, nil
+}
+
+// parseBig parses an integer in the syntax of Go integer literals.
+func parseBig(s string) (*big.Int, error) {
+	x, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		return nil, fmt.Errorf("invalid integer %q", s)
+	}
+	return x, nil
+}
+
+// integerOf returns the absolute value of v and whether it is negative if v is
+// of a Go integer type.
+func integerOf(v interface{}) (abs uint64, negative bool, ok bool) {
+	var i int64
+	switch x := v.(type) {
+	case uint8:
+		return uint64(x), false, true
+	case uint16:
+		return uint64(x), false, true
+	case uint32:
+		return uint64(x), false, true
+	case uint64:
+		return x, false, true
+	case uint:
+		return uint64(x), false, true
+	case uintptr:
+		return uint64(x), false, true
+	case int8:
+		i = int64(x)
+	case int16:
+		i = int64(x)
+	case int32:
+		i = int64(x)
+	case int64:
+		i = x
+	case int:
+		i = int64(x)
+	default:
+		return 0, false, false
+	}
+	if i < 0 {
+		return uint64(-i), true, true
+	}
+	return uint64(i), false, true
+}
+
+// bits returns the width of the integer field in bits.
+func (f *Field) bits() uint64 {
+	if f.BitFieldLen != 0 {
+		return uint64(f.BitFieldLen)
+	}
+	return 8 * f.Len
+}
+
+// encodeInteger converts v of any Go integer type to the Value of the integer
+// field after checking its range.
+func (f *Field) encodeInteger(v interface{}) (Value, error) {
+	abs, negative, ok := integerOf(v)
+	if !ok {
+		return nil, fmt.Errorf("value of type %T cannot be stored in a field of kind %s",
+			v, f.Kind)
+	}
+	bits := f.bits()
+	signed := f.BitFieldLen == 0 && f.Kind.Signed()
+	var limit uint64
+	switch {
+	case signed && negative:
+		limit = uint64(1) << (bits - 1)
+	case signed:
+		limit = uint64(1)<<(bits-1) - 1
+	case negative:
+		limit = 0
+	default:
+		limit = uint64(1)<<bits - 1
+	}
+	if abs > limit {
+		if negative {
+			return nil, fmt.Errorf("-%d: %w", abs, ErrOverflow)
+		}
+		return nil, fmt.Errorf("%d: %w", abs, ErrOverflow)
+	}
+	u := abs
+	if negative {
+		u = -abs
+	}
+	return f.uintValue(u), nil
+}
@@ -0,0 +1,77 @@
+package bmstruct
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Typed access", func() {
+	var tmpl *Template
+	var s *Struct
+
+	BeforeEach(func() {
+		tmpl = NewTemplate(-1,
+			Uint8Field("u8", 0),
+			Int16Field("i16", 1),
+			BitField("bits", 3, 2, 3),
+			Float32Field("f32", 4),
+			(&Template{Size: 2}).Field("raw", 8),
+		)
+		s = tmpl.Empty()
+	})
+	It("should return the Go type of the field", func() {
+		Expect(s.Set("u8", 200)).To(Succeed())
+		Expect(s.Set("i16", int8(-3))).To(Succeed())
+		Expect(s.Set("bits", uint64(5))).To(Succeed())
+		Expect(s.Set("f32", 1.5)).To(Succeed())
+		Expect(s.Set("raw", Value{1, 2})).To(Succeed())
+		Expect(s.Get("u8")).To(Equal(uint8(200)))
+		Expect(s.Get("i16")).To(Equal(int16(-3)))
+		Expect(s.Get("bits")).To(Equal(uint8(5)))
+		Expect(s.Get("f32")).To(Equal(float32(1.5)))
+		Expect(s.Get("raw")).To(Equal(Value{1, 2}))
+		Expect(s.Value[3]).To(Equal(byte(5 << 2)))
+	})
+	It("should report overflows", func() {
+		for field, v := range map[string]interface{}{
+			"u8":   256,
+			"i16":  40000,
+			"bits": 8,
+			"f32":  1e100,
+		} {
+			err := s.Set(field, v)
+			Expect(errors.Is(err, ErrOverflow)).To(BeTrue(), field)
+		}
+		Expect(errors.Is(s.Set("u8", -1), ErrOverflow)).To(BeTrue())
+		Expect(s.Set("i16", -32768)).To(Succeed())
+		Expect(errors.Is(s.Set("i16", -32769), ErrOverflow)).To(BeTrue())
+		Expect(s.Value).To(Equal(Value{0, 0, 0x80, 0, 0, 0, 0, 0, 0, 0}))
+	})
+	It("should report incorrect types and sizes", func() {
+		Expect(s.Set("u8", "1")).NotTo(Succeed())
+		Expect(s.Set("f32", 1)).NotTo(Succeed())
+		Expect(s.Set("raw", Value{1})).NotTo(Succeed())
+		Expect(func() {
+			s.Set("no-such-field", 1)
+		}).To(Panic())
+		Expect(func() {
+			s.Get("no-such-field")
+		}).To(Panic())
+	})
+	It("should insert optional and resize dynamic fields", func() {
+		t := NewTemplate(-1,
+			BitField("flag", 0, 0, 1),
+			Uint8Field("len", 1),
+			OptionalField(Uint16Field("opt", 2), "flag"),
+			DynamicField("body", 2, "len"),
+		)
+		s := t.Empty()
+		Expect(s.Get("opt")).To(BeNil())
+		Expect(s.Set("opt", 0x1234)).To(Succeed())
+		Expect(s.Set("body", Value("abc"))).To(Succeed())
+		Expect(s.Value).To(Equal(Value{1, 3, 0x34, 0x12, 'a', 'b', 'c'}))
+		Expect(s.Get("opt")).To(Equal(uint16(0x1234)))
+	})
+})
@@ -1,7 +1,9 @@
 package bmstruct
 
 import (
-	"reflect"
+	"encoding/binary"
+	"fmt"
+	"math"
 	"unsafe"
 )
 
@@ -36,6 +38,9 @@ func IsNil(v Valuable) bool {
 	return v.Address() == uintptr(0)
 }
 
+// uintptrSize is the size of uintptr in bytes.
+const uintptrSize = int(unsafe.Sizeof(uintptr(0)))
+
 //Value is a byte slice with extra capabilities. A Value can convert itself into
 //the most common go types (int, []byte, string, etc.) and vice versa the most
 //common go types can be converted easily to Value.
@@ -46,160 +51,340 @@ func (v Value) GetValue() Value {
 	return v
 }
 
-func intValue(v interface{}) Value {
-	t := reflect.TypeOf(v)
-	bs := make([]byte, t.Size())
-	for n := range bs {
-		bs[n] = nthByteOfInt(v, n)
+// checkLen panics if the length of the Value is not n. The message is built
+// only on failure so that the conversions do not allocate.
+func (v Value) checkLen(n int, typeName string) {
+	if len(v) != n {
+		panic("value cannot be converted to " + typeName + ", size mismatch")
 	}
-	return bs
 }
 
 //Uint8 function converts an uint8 value to Value type.
 func Uint8(v uint8) Value {
-	return intValue(v)
+	b := make(Value, 1)
+	PutUint8(b, v)
+	return b
+}
+
+//PutUint8 function writes v into dst without allocating a new Value. The
+//function will panic if the length of dst is incorrect.
+func PutUint8(dst Value, v uint8) {
+	dst.checkLen(1, "Uint8")
+	dst[0] = v
 }
 
 //Uint8 method returns the uint8 representation of a Value. The method will
 //panic if the Value's length is incorrect.
 func (v Value) Uint8() uint8 {
-	if len(v) != 1 {
-		panic("value cannot be converted to Uint8, size mismatch")
-	}
+	v.checkLen(1, "Uint8")
 	return v[0]
 }
 
+//Bool function converts a bool value to Value type: false is 0, true is 1.
+func Bool(v bool) Value {
+	b := make(Value, 1)
+	PutBool(b, v)
+	return b
+}
+
+//PutBool function writes v into dst without allocating a new Value. The
+//function will panic if the length of dst is incorrect.
+func PutBool(dst Value, v bool) {
+	dst.checkLen(1, "Bool")
+	dst[0] = 0
+	if v {
+		dst[0] = 1
+	}
+}
+
+//Bool method returns the bool representation of a Value: any value other than
+//0 is true. The method will panic if the Value's length is incorrect.
+func (v Value) Bool() bool {
+	v.checkLen(1, "Bool")
+	return v[0] != 0
+}
+
 //Int8 function converts an int8 value to Value type.
 func Int8(v int8) Value {
-	return intValue(v)
+	b := make(Value, 1)
+	PutInt8(b, v)
+	return b
+}
+
+//PutInt8 function writes v into dst without allocating a new Value. The
+//function will panic if the length of dst is incorrect.
+func PutInt8(dst Value, v int8) {
+	dst.checkLen(1, "Int8")
+	dst[0] = byte(v)
 }
 
 //Int8 method returns the int8 representation of a Value. The method will
 //panic if the Value's length is incorrect.
 func (v Value) Int8() int8 {
-	if len(v) != 1 {
-		panic("value cannot be converted to Int8, size mismatch")
-	}
+	v.checkLen(1, "Int8")
 	return int8(v[0])
 }
 
 //Uint16 function converts an uint16 value to Value type.
 func Uint16(v uint16) Value {
-	return intValue(v)
+	b := make(Value, 2)
+	PutUint16(b, v)
+	return b
+}
+
+//PutUint16 function writes v into dst without allocating a new Value. The
+//function will panic if the length of dst is incorrect.
+func PutUint16(dst Value, v uint16) {
+	dst.checkLen(2, "Uint16")
+	binary.LittleEndian.PutUint16(dst, v)
 }
 
 //Uint16 method returns the uint16 representation of a Value. The method will
 //panic if the Value's length is incorrect.
 func (v Value) Uint16() uint16 {
-	var i uint16
-	t := reflect.TypeOf(i)
-	if uint64(t.Size()) != uint64(len(v)) {
-		panic("value cannot be converted to Uint16, size mismatch")
-	}
-	for n := 0; n < int(t.Size()); n++ {
-		i |= uint16(v[n]) << uint(n*8)
-	}
-	return i
+	v.checkLen(2, "Uint16")
+	return binary.LittleEndian.Uint16(v)
 }
 
 //Int16 function converts an int16 value to Value type.
 func Int16(v int16) Value {
-	return intValue(v)
+	b := make(Value, 2)
+	PutInt16(b, v)
+	return b
+}
+
+//PutInt16 function writes v into dst without allocating a new Value. The
+//function will panic if the length of dst is incorrect.
+func PutInt16(dst Value, v int16) {
+	dst.checkLen(2, "Int16")
+	binary.LittleEndian.PutUint16(dst, uint16(v))
 }
 
 //Int16 method returns the int16 representation of a Value. The method will
 //panic if the Value's length is incorrect.
 func (v Value) Int16() int16 {
-	var i int16
-	t := reflect.TypeOf(i)
-	if uint64(t.Size()) != uint64(len(v)) {
-		panic("value cannot be converted to Int16, size mismatch")
-	}
-	for n := 0; n < int(t.Size()); n++ {
-		i |= int16(v[n]) << uint(n*8)
-	}
-	return i
+	v.checkLen(2, "Int16")
+	return int16(binary.LittleEndian.Uint16(v))
 }
 
 //Uint32 function converts an uint32 value to Value type.
 func Uint32(v uint32) Value {
-	return intValue(v)
+	b := make(Value, 4)
+	PutUint32(b, v)
+	return b
+}
+
+//PutUint32 function writes v into dst without allocating a new Value. The
+//function will panic if the length of dst is incorrect.
+func PutUint32(dst Value, v uint32) {
+	dst.checkLen(4, "Uint32")
+	binary.LittleEndian.PutUint32(dst, v)
 }
 
 //Uint32 method returns the uint32 representation of a Value. The method will
 //panic if the Value's length is incorrect.
 func (v Value) Uint32() uint32 {
-	var i uint32
-	t := reflect.TypeOf(i)
-	if uint64(t.Size()) != uint64(len(v)) {
-		panic("value cannot be converted to Uint32, size mismatch")
-	}
-	for n := 0; n < int(t.Size()); n++ {
-		i |= uint32(v[n]) << uint(n*8)
-	}
-	return i
+	v.checkLen(4, "Uint32")
+	return binary.LittleEndian.Uint32(v)
 }
 
 //Int32 function converts an int32 value to Value type.
 func Int32(v int32) Value {
-	return intValue(v)
+	b := make(Value, 4)
+	PutInt32(b, v)
+	return b
+}
+
+//PutInt32 function writes v into dst without allocating a new Value. The
+//function will panic if the length of dst is incorrect.
+func PutInt32(dst Value, v int32) {
+	dst.checkLen(4, "Int32")
+	binary.LittleEndian.PutUint32(dst, uint32(v))
 }
 
 //Int32 method returns the int32 representation of a Value. The method will
 //panic if the Value's length is incorrect.
 func (v Value) Int32() int32 {
-	var i int32
-	t := reflect.TypeOf(i)
-	if uint64(t.Size()) != uint64(len(v)) {
-		panic("value cannot be converted to Int32, size mismatch")
-	}
-	for n := 0; n < int(t.Size()); n++ {
-		i |= int32(v[n]) << uint(n*8)
-	}
-	return i
+	v.checkLen(4, "Int32")
+	return int32(binary.LittleEndian.Uint32(v))
 }
 
 //Uint64 function converts an uint64 value to Value type.
 func Uint64(v uint64) Value {
-	return intValue(v)
+	b := make(Value, 8)
+	PutUint64(b, v)
+	return b
+}
+
+//PutUint64 function writes v into dst without allocating a new Value. The
+//function will panic if the length of dst is incorrect.
+func PutUint64(dst Value, v uint64) {
+	dst.checkLen(8, "Uint64")
+	binary.LittleEndian.PutUint64(dst, v)
 }
 
 //Uint64 method returns the uint64 representation of a Value. The method will
 //panic if the Value's length is incorrect.
 func (v Value) Uint64() uint64 {
-	var i uint64
-	t := reflect.TypeOf(i)
-	if uint64(t.Size()) != uint64(len(v)) {
-		panic("value cannot be converted to Uint64, size mismatch")
-	}
-	for n := 0; n < int(t.Size()); n++ {
-		i |= uint64(v[n]) << uint(n*8)
-	}
-	return i
+	v.checkLen(8, "Uint64")
+	return binary.LittleEndian.Uint64(v)
 }
 
 //Int64 function converts an int64 value to Value type.
 func Int64(v int64) Value {
-	return intValue(v)
+	b := make(Value, 8)
+	PutInt64(b, v)
+	return b
+}
+
+//PutInt64 function writes v into dst without allocating a new Value. The
+//function will panic if the length of dst is incorrect.
+func PutInt64(dst Value, v int64) {
+	dst.checkLen(8, "Int64")
+	binary.LittleEndian.PutUint64(dst, uint64(v))
 }
 
 //Int64 method returns the int64 representation of a Value. The method will
 //panic if the Value's length is incorrect.
 func (v Value) Int64() int64 {
-	var i int64
-	t := reflect.TypeOf(i)
-	if uint64(t.Size()) != uint64(len(v)) {
-		panic("value cannot be converted to Uint64, size mismatch")
+	v.checkLen(8, "Int64")
+	return int64(binary.LittleEndian.Uint64(v))
+}
+
+// checkIntLen panics if n is not a valid integer length, i.e. 1 to 8 bytes.
+func checkIntLen(n int) {
+	if n < 1 || n > 8 {
+		panic(fmt.Sprintf("invalid integer length %d", n))
 	}
-	for n := 0; n < int(t.Size()); n++ {
-		i |= int64(v[n]) << uint(n*8)
+}
+
+//UintBytes function converts an unsigned integer to a Value of n bytes, where
+//n is 1 to 8, in little-endian order like the other conversions. The function
+//will panic for an invalid n or if v does not fit into n bytes.
+func UintBytes(v uint64, n int) Value {
+	b := make(Value, n)
+	PutUintBytes(b, v)
+	return b
+}
+
+//PutUintBytes function writes v into dst of 1 to 8 bytes without allocating a
+//new Value. The function will panic if the length of dst is invalid or v does
+//not fit into it.
+func PutUintBytes(dst Value, v uint64) {
+	checkUintBytes(dst, v)
+	putUintBytes(dst, v)
+}
+
+//UintBytes method returns the unsigned integer representation of a Value of 1
+//to 8 bytes, e.g. of a 24-bit integer. The method will panic if the Value's
+//length is invalid.
+func (v Value) UintBytes() uint64 {
+	checkIntLen(len(v))
+	return uintOfBytes(v)
+}
+
+//UintBytesBE function is the big-endian counterpart of UintBytes, e.g. for
+//integers in network byte order.
+func UintBytesBE(v uint64, n int) Value {
+	b := make(Value, n)
+	PutUintBytesBE(b, v)
+	return b
+}
+
+//PutUintBytesBE function is the big-endian counterpart of PutUintBytes.
+func PutUintBytesBE(dst Value, v uint64) {
+	checkUintBytes(dst, v)
+	putUintBytesBE(dst, v)
+}
+
+//UintBytesBE method is the big-endian counterpart of the UintBytes method.
+func (v Value) UintBytesBE() uint64 {
+	checkIntLen(len(v))
+	return uintOfBytesBE(v)
+}
+
+// checkUintBytes panics if the length of dst is invalid or v does not fit into
+// it.
+func checkUintBytes(dst Value, v uint64) {
+	checkIntLen(len(dst))
+	if len(dst) < 8 && v >= uint64(1)<<(8*len(dst)) {
+		panic(fmt.Sprintf("%d does not fit into %d bytes", v, len(dst)))
 	}
-	return i
+}
+
+//IntBytes function converts a signed integer to a Value of n bytes, where n is
+//1 to 8, in little-endian two's complement representation. The function will
+//panic for an invalid n or if v does not fit into n bytes.
+func IntBytes(v int64, n int) Value {
+	b := make(Value, n)
+	PutIntBytes(b, v)
+	return b
+}
+
+//PutIntBytes function writes v into dst of 1 to 8 bytes without allocating a
+//new Value. The function will panic if the length of dst is invalid or v does
+//not fit into it.
+func PutIntBytes(dst Value, v int64) {
+	checkIntBytes(dst, v)
+	putUintBytes(dst, uint64(v))
+}
+
+//IntBytes method returns the sign-extended integer representation of a Value of
+//1 to 8 bytes, e.g. of a 24-bit integer. The method will panic if the Value's
+//length is invalid.
+func (v Value) IntBytes() int64 {
+	checkIntLen(len(v))
+	return signExtend(uintOfBytes(v), len(v))
+}
+
+//IntBytesBE function is the big-endian counterpart of IntBytes.
+func IntBytesBE(v int64, n int) Value {
+	b := make(Value, n)
+	PutIntBytesBE(b, v)
+	return b
+}
+
+//PutIntBytesBE function is the big-endian counterpart of PutIntBytes.
+func PutIntBytesBE(dst Value, v int64) {
+	checkIntBytes(dst, v)
+	putUintBytesBE(dst, uint64(v))
+}
+
+//IntBytesBE method is the big-endian counterpart of the IntBytes method.
+func (v Value) IntBytesBE() int64 {
+	checkIntLen(len(v))
+	return signExtend(uintOfBytesBE(v), len(v))
+}
+
+// checkIntBytes panics if the length of dst is invalid or v does not fit into
+// it.
+func checkIntBytes(dst Value, v int64) {
+	checkIntLen(len(dst))
+	shift := uint(64 - 8*len(dst))
+	if v<<shift>>shift != v {
+		panic(fmt.Sprintf("%d does not fit into %d bytes", v, len(dst)))
+	}
+}
+
+// signExtend returns the integer of n bytes stored in the lowest bytes of u
+// sign-extended to int64.
+func signExtend(u uint64, n int) int64 {
+	shift := uint(64 - 8*n)
+	return int64(u<<shift) >> shift
 }
 
 //Uintptr function converts an uintptr value to Value type.
 func Uintptr(v uintptr) Value {
-	return intValue(v)
+	b := make(Value, uintptrSize)
+	PutUintptr(b, v)
+	return b
+}
+
+//PutUintptr function writes v into dst without allocating a new Value. The
+//function will panic if the length of dst is incorrect.
+func PutUintptr(dst Value, v uintptr) {
+	dst.checkLen(uintptrSize, "Uintptr")
+	putUintBytes(dst, uint64(v))
 }
 
 //Uintptr method returns the uintptr representation of a Value. The method will
@@ -211,15 +396,50 @@ func Uintptr(v uintptr) Value {
 //If you want to get the pointer to the byte slice backing the Value use the
 //Address() method instead.
 func (v Value) Uintptr() uintptr {
-	var i uintptr
-	t := reflect.TypeOf(i)
-	if uint64(t.Size()) != uint64(len(v)) {
-		panic("value cannot be converted to Uint64, size mismatch")
-	}
-	for n := 0; n < int(t.Size()); n++ {
-		i |= uintptr(v[n]) << uint(n*8)
-	}
-	return i
+	v.checkLen(uintptrSize, "Uintptr")
+	return uintptr(uintOfBytes(v))
+}
+
+//Float32 function converts a float32 value to Value type.
+func Float32(v float32) Value {
+	b := make(Value, 4)
+	PutFloat32(b, v)
+	return b
+}
+
+//PutFloat32 function writes v into dst without allocating a new Value. The
+//function will panic if the length of dst is incorrect.
+func PutFloat32(dst Value, v float32) {
+	dst.checkLen(4, "Float32")
+	binary.LittleEndian.PutUint32(dst, math.Float32bits(v))
+}
+
+//Float32 method returns the float32 representation of a Value. The method will
+//panic if the Value's length is incorrect.
+func (v Value) Float32() float32 {
+	v.checkLen(4, "Float32")
+	return math.Float32frombits(binary.LittleEndian.Uint32(v))
+}
+
+//Float64 function converts a float64 value to Value type.
+func Float64(v float64) Value {
+	b := make(Value, 8)
+	PutFloat64(b, v)
+	return b
+}
+
+//PutFloat64 function writes v into dst without allocating a new Value. The
+//function will panic if the length of dst is incorrect.
+func PutFloat64(dst Value, v float64) {
+	dst.checkLen(8, "Float64")
+	binary.LittleEndian.PutUint64(dst, math.Float64bits(v))
+}
+
+//Float64 method returns the float64 representation of a Value. The method will
+//panic if the Value's length is incorrect.
+func (v Value) Float64() float64 {
+	v.checkLen(8, "Float64")
+	return math.Float64frombits(binary.LittleEndian.Uint64(v))
 }
 
 //ByteSlice function converts an []byte value to Value type. The value of 'b'
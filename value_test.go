@@ -2,6 +2,8 @@ package bmstruct
 
 import (
 	"fmt"
+	"testing"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -49,6 +51,25 @@ var _ = Describe("Value", func() {
 			}).To(Panic())
 		})
 	})
+	Describe("Bool", func() {
+		It("should generate the expected Value", func() {
+			Expect(Bool(false)).To(Equal(Value{0}))
+			Expect(Bool(true)).To(Equal(Value{1}))
+		})
+		It("should convert in both direction properly", func() {
+			Expect(Bool(false).Bool()).To(BeFalse())
+			Expect(Bool(true).Bool()).To(BeTrue())
+			Expect(Value{0x80}.Bool()).To(BeTrue())
+		})
+		It("should panic when Value's length is incorrect", func() {
+			Expect(func() {
+				Value{1, 2}.Bool()
+			}).To(Panic())
+			Expect(func() {
+				PutBool(Value{}, true)
+			}).To(Panic())
+		})
+	})
 	Describe("Int8", func() {
 		It("should generate the expected Value", func() {
 			Expect(Int8(0)).To(Equal(Value{0}))
@@ -207,6 +228,53 @@ var _ = Describe("Value", func() {
 			}).To(Panic())
 		})
 	})
+	Describe("UintBytes", func() {
+		It("should generate the expected Value", func() {
+			Expect(UintBytes(0x123456, 3)).To(Equal(Value{0x56, 0x34, 0x12}))
+			Expect(UintBytes(0, 1)).To(Equal(Value{0}))
+			Expect(UintBytes(1<<63, 8)).To(Equal(Value{0, 0, 0, 0, 0, 0, 0, 0x80}))
+		})
+		It("should convert in both direction properly", func() {
+			Expect(UintBytes(0xabcdef0123, 5).UintBytes()).To(Equal(uint64(0xabcdef0123)))
+		})
+		It("should panic for invalid lengths and values", func() {
+			Expect(func() { Value{}.UintBytes() }).To(Panic())
+			Expect(func() { make(Value, 9).UintBytes() }).To(Panic())
+			Expect(func() { UintBytes(0x1000000, 3) }).To(Panic())
+		})
+	})
+	Describe("IntBytes", func() {
+		It("should generate the expected Value", func() {
+			Expect(IntBytes(-1, 3)).To(Equal(Value{0xff, 0xff, 0xff}))
+			Expect(IntBytes(0x7fffff, 3)).To(Equal(Value{0xff, 0xff, 0x7f}))
+		})
+		It("should convert in both direction properly", func() {
+			Expect(IntBytes(-0x800000, 3).IntBytes()).To(Equal(int64(-0x800000)))
+			Expect(IntBytes(-42, 7).IntBytes()).To(Equal(int64(-42)))
+			Expect(IntBytes(-42, 8).IntBytes()).To(Equal(int64(-42)))
+		})
+		It("should panic for invalid lengths and values", func() {
+			Expect(func() { Value{}.IntBytes() }).To(Panic())
+			Expect(func() { IntBytes(0x800000, 3) }).To(Panic())
+			Expect(func() { IntBytes(-0x800001, 3) }).To(Panic())
+		})
+	})
+	Describe("UintBytesBE and IntBytesBE", func() {
+		It("should generate the expected Value", func() {
+			Expect(UintBytesBE(0x123456, 3)).To(Equal(Value{0x12, 0x34, 0x56}))
+			Expect(IntBytesBE(-2, 3)).To(Equal(Value{0xff, 0xff, 0xfe}))
+		})
+		It("should convert in both direction properly", func() {
+			Expect(UintBytesBE(0xabcdef0123, 5).UintBytesBE()).To(Equal(uint64(0xabcdef0123)))
+			Expect(IntBytesBE(-0x800000, 3).IntBytesBE()).To(Equal(int64(-0x800000)))
+			Expect(IntBytesBE(-42, 8).IntBytesBE()).To(Equal(int64(-42)))
+		})
+		It("should panic for invalid lengths and values", func() {
+			Expect(func() { make(Value, 9).UintBytesBE() }).To(Panic())
+			Expect(func() { UintBytesBE(0x1000000, 3) }).To(Panic())
+			Expect(func() { IntBytesBE(0x800000, 3) }).To(Panic())
+		})
+	})
 	Describe("Uintptr", func() {
 		It("should generate the expected Value", func() {
 			Expect(Uintptr(0)).To(Equal(Value{0, 0, 0, 0, 0, 0, 0, 0}))
@@ -230,6 +298,70 @@ var _ = Describe("Value", func() {
 			}).To(Panic())
 		})
 	})
+	Describe("Put functions", func() {
+		It("should write the value into the destination", func() {
+			v := make(Value, 8)
+			PutUint8(v[0:1], 0xfe)
+			PutInt8(v[1:2], -2)
+			PutUint16(v[2:4], 0x0102)
+			PutInt16(v[4:6], -2)
+			Expect(v).To(Equal(Value{0xfe, 0xfe, 2, 1, 0xfe, 0xff, 0, 0}))
+			PutUint32(v[0:4], 0x01020304)
+			PutInt32(v[4:8], -2)
+			Expect(v).To(Equal(Value{4, 3, 2, 1, 0xfe, 0xff, 0xff, 0xff}))
+			PutUint64(v, 0x0102030405060708)
+			Expect(v).To(Equal(Value{8, 7, 6, 5, 4, 3, 2, 1}))
+			PutInt64(v, -2)
+			Expect(v.Int64()).To(Equal(int64(-2)))
+			PutUintptr(v, 42)
+			Expect(v.Uintptr()).To(Equal(uintptr(42)))
+		})
+		It("should panic when the destination's length is incorrect", func() {
+			Expect(func() {
+				PutUint16(make(Value, 4), 1)
+			}).To(Panic())
+			Expect(func() {
+				PutInt64(make(Value, 4), 1)
+			}).To(Panic())
+		})
+		It("should not allocate", func() {
+			v := make(Value, 8)
+			Expect(testing.AllocsPerRun(100, func() {
+				PutUint32(v[0:4], 42)
+				PutInt64(v, -42)
+				_ = v[0:4].Uint32()
+				_ = v.Int64()
+			})).To(BeZero())
+		})
+	})
+	Describe("Float32", func() {
+		It("should generate the expected Value", func() {
+			Expect(Float32(0)).To(Equal(Value{0, 0, 0, 0}))
+			Expect(Float32(1)).To(Equal(Value{0, 0, 0x80, 0x3f}))
+			Expect(Float32(-2)).To(Equal(Value{0, 0, 0, 0xc0}))
+		})
+		It("should convert in both direction properly", func() {
+			Expect(Float32(3.25).Float32()).To(Equal(float32(3.25)))
+		})
+		It("should panic when Value's length is incorrect", func() {
+			Expect(func() {
+				Value{1, 2}.Float32()
+			}).To(Panic())
+		})
+	})
+	Describe("Float64", func() {
+		It("should generate the expected Value", func() {
+			Expect(Float64(1)).To(Equal(Value{0, 0, 0, 0, 0, 0, 0xf0, 0x3f}))
+		})
+		It("should convert in both direction properly", func() {
+			Expect(Float64(-3.25).Float64()).To(Equal(-3.25))
+		})
+		It("should panic when Value's length is incorrect", func() {
+			Expect(func() {
+				Value{1, 2, 3, 4}.Float64()
+			}).To(Panic())
+		})
+	})
 	Describe("ByteSlice", func() {
 		It("should generate the expected Value", func() {
 			Expect(ByteSlice([]byte{1, 2, 3, 4, 5})).To(Equal(Value{1, 2, 3, 4, 5}))
@@ -0,0 +1,175 @@
+package bmstruct
+
+import (
+	"fmt"
+	"strings"
+)
+
+//Case is one of the possible interpretations of a variant Field. The Case is
+//active when the discriminator field of the variant equals to Value.
+type Case struct {
+	Name     string    `json:"name"`
+	Value    uint64    `json:"value"`
+	Template *Template `json:"template"`
+}
+
+//VariantField function creates a new Field of VariantKind with the given name
+//and offset. The discriminator is the name of an integer field of the same
+//Template that decides which of the given cases is active. Len is calculated to
+//fit the largest case.
+//
+//The Fields of the active case can be reached with the Lookup and Update
+//methods of Struct using paths like "payload.icmp.type" where "payload" is the
+//name of the variant Field and "icmp" is the name of the Case.
+//
+//VariantField panics when no cases, cases with duplicate names or values or
+//cases with dynamic fields are given.
+func VariantField(name string, offset uint64, discriminator string,
+	cases ...*Case) *Field {
+	if len(cases) == 0 {
+		panic("at least 1 case shall be specified")
+	}
+	names := make(map[string]bool)
+	values := make(map[uint64]bool)
+	length := 0
+	for _, c := range cases {
+		if c.Template.dynamic() {
+			panic(fmt.Sprintf("template of case %s shall have a fixed size", c.Name))
+		}
+		if names[c.Name] || values[c.Value] {
+			panic(fmt.Sprintf("duplicate case %s (%d)", c.Name, c.Value))
+		}
+		names[c.Name], values[c.Value] = true, true
+		if c.Template.Size > length {
+			length = c.Template.Size
+		}
+	}
+	return &Field{
+		Name:          name,
+		Offset:        offset,
+		Len:           uint64(length),
+		Kind:          VariantKind,
+		Discriminator: discriminator,
+		Cases:         cases,
+	}
+}
+
+// activeCase returns the active Case of the variant field. The data and the
+// Fields shall be the ones that contain the field. It returns nil if no Case
+// matches the discriminator.
+func (f *Field) activeCase(fields map[string]*Field, data []byte) *Case {
+	value := fields[f.Discriminator].uintOf(data)
+	for _, c := range f.Cases {
+		if c.Value == value {
+			return c
+		}
+	}
+	return nil
+}
+
+// resolved is a Field found by Template.resolve together with the Template
+// that contains the Field and the offset of that Template in the data.
+type resolved struct {
+	field  *Field
+	parent *Template
+	base   uint64
+}
+
+// resolve returns the Field indicated by path. The path is either the name of a
+// Field of the Template or a dot separated path that refers to a Field of a
+// nested Template (e.g. "grades.math") or of the active Case of a variant Field
+// (e.g. "payload.icmp.type"). The Offset of the returned Field is relative to
+// the beginning of data. The returned error wraps ErrFieldAbsent if the path
+// refers to an absent optional Field.
+func (t *Template) resolve(data []byte, path string) (resolved, error) {
+	if field, found := t.Fields[path]; found && !field.dynamic() && !t.shifted(field) {
+		return resolved{field: field, parent: t}, nil
+	}
+	fields, absent := t.Fields, map[string]*Field(nil)
+	if t.dynamic() {
+		l := t.layout(data)
+		if l.err != nil {
+			return resolved{}, l.err
+		}
+		if !l.complete || l.size > len(data) {
+			return resolved{}, fmt.Errorf("data (%d bytes) is shorter than the record (%d bytes)",
+				len(data), l.size)
+		}
+		fields, absent = l.fields, l.absent
+	}
+	if field, found := fields[path]; found {
+		return resolved{field: field, parent: t}, nil
+	}
+	if _, found := absent[path]; found {
+		return resolved{}, fmt.Errorf("field %s: %w", path, ErrFieldAbsent)
+	}
+	for i := strings.IndexByte(path, '.'); i >= 0; {
+		field, found := fields[path[:i]]
+		rest := path[i+1:]
+		if _, found := absent[path[:i]]; found {
+			return resolved{}, fmt.Errorf("field %s: %w", path[:i], ErrFieldAbsent)
+		}
+		if found && field.Template != nil {
+			r, err := field.Template.resolve(data[field.Offset:field.Offset+field.Len], rest)
+			return r.shift(field.Offset), err
+		}
+		if found && field.Kind == VariantKind {
+			caseName, casePath := rest, ""
+			if j := strings.IndexByte(rest, '.'); j >= 0 {
+				caseName, casePath = rest[:j], rest[j+1:]
+			}
+			c := field.activeCase(fields, data)
+			if c == nil || c.Name != caseName {
+				return resolved{}, fmt.Errorf("case %s of variant %s is not active",
+					caseName, field.Name)
+			}
+			if casePath == "" {
+				return resolved{
+					field: &Field{
+						Name:     path,
+						Offset:   field.Offset,
+						Len:      uint64(c.Template.Size),
+						Template: c.Template,
+					},
+					parent: t,
+				}, nil
+			}
+			caseData := data[field.Offset : field.Offset+uint64(c.Template.Size)]
+			r, err := c.Template.resolve(caseData, casePath)
+			return r.shift(field.Offset), err
+		}
+		next := strings.IndexByte(rest, '.')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return resolved{}, fmt.Errorf("field name %s not found in template", path)
+}
+
+// shift returns the resolved Field moved by offset.
+func (r resolved) shift(offset uint64) resolved {
+	if r.field == nil || offset == 0 {
+		return r
+	}
+	field := *r.field
+	field.Offset += offset
+	r.field = &field
+	r.base += offset
+	return r
+}
+
+//Variant method returns the active Case of the variant Field indicated by
+//fieldName or nil if the discriminator does not match any Case.
+//
+//Variant panics for a non-existing field name or for a Field that is not a
+//variant.
+func (s *Struct) Variant(fieldName string) *Case {
+	r := s.resolve(fieldName)
+	if r.field.Kind != VariantKind {
+		panic(fmt.Sprintf("field %s is not a variant", fieldName))
+	}
+	data := s.Value[r.base:]
+	fields := r.parent.fieldsOf(data)
+	return fields[r.field.Name].activeCase(fields, data)
+}
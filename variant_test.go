@@ -0,0 +1,119 @@
+package bmstruct
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Variant", func() {
+	var icmp, udp, tmpl *Template
+	var s *Struct
+
+	BeforeEach(func() {
+		icmp = NewTemplate(-1,
+			Uint8Field("type", 0),
+			Uint8Field("code", 1),
+		)
+		udp = NewTemplate(-1,
+			Uint16Field("sport", 0),
+			Uint16Field("dport", 2),
+		)
+		tmpl = NewTemplate(-1,
+			Uint8Field("proto", 0),
+			VariantField("payload", 1, "proto",
+				&Case{Name: "icmp", Value: 1, Template: icmp},
+				&Case{Name: "udp", Value: 17, Template: udp},
+			),
+		)
+		s = tmpl.New(Value{1, 8, 0, 0, 0})
+	})
+	It("should fit the largest case", func() {
+		Expect(tmpl.Size).To(Equal(5))
+		Expect(tmpl.Fields["payload"].Kind).To(Equal(VariantKind))
+	})
+	It("should report the active case", func() {
+		Expect(s.Variant("payload").Name).To(Equal("icmp"))
+		s.Update("proto", Uint8(17))
+		Expect(s.Variant("payload").Name).To(Equal("udp"))
+		s.Update("proto", Uint8(6))
+		Expect(s.Variant("payload")).To(BeNil())
+	})
+	It("should look up the fields of the active case only", func() {
+		Expect(s.Lookup("payload.icmp.type").Uint8()).To(Equal(uint8(8)))
+		Expect(s.Lookup("payload.icmp")).To(Equal(Value{8, 0}))
+		Expect(func() {
+			s.Lookup("payload.udp.dport")
+		}).To(Panic())
+		s.Update("proto", Uint8(17))
+		s.Update("payload.udp.dport", Uint16(53))
+		Expect(s.Value).To(Equal(Value{17, 8, 0, 53, 0}))
+		Expect(func() {
+			s.Lookup("payload.icmp.type")
+		}).To(Panic())
+	})
+	It("should resolve the active case on each call of the returned functions", func() {
+		lookup := s.LookupFunc("payload.icmp.code")
+		update := s.UpdateFunc("payload.icmp.code")
+		update(Uint8(3))
+		Expect(lookup().Uint8()).To(Equal(uint8(3)))
+		s.Update("proto", Uint8(17))
+		Expect(func() {
+			lookup()
+		}).To(Panic())
+	})
+	It("should work in nested templates", func() {
+		outer := NewTemplate(-1,
+			Uint16Field("id", 0),
+			tmpl.Field("packet", 2),
+		)
+		o := outer.New(Value{0, 0, 17, 0, 0, 1, 0})
+		Expect(o.Lookup("packet.proto").Uint8()).To(Equal(uint8(17)))
+		Expect(o.Lookup("packet.payload.udp.dport").Uint16()).To(Equal(uint16(1)))
+		Expect(o.Variant("packet.payload").Name).To(Equal("udp"))
+	})
+	It("should be walked with every case", func() {
+		paths := []string{}
+		tmpl.Walk(func(path string, offset uint64, field *Field) {
+			paths = append(paths, path)
+		})
+		Expect(paths).To(Equal([]string{
+			"proto", "payload",
+			"payload.icmp.type", "payload.icmp.code",
+			"payload.udp.sport", "payload.udp.dport",
+		}))
+	})
+	It("should panic for invalid definitions", func() {
+		Expect(func() {
+			VariantField("payload", 1, "proto")
+		}).To(Panic())
+		Expect(func() {
+			VariantField("payload", 1, "proto",
+				&Case{Name: "icmp", Value: 1, Template: icmp},
+				&Case{Name: "icmp", Value: 2, Template: udp},
+			)
+		}).To(Panic())
+		Expect(func() {
+			NewTemplate(-1, VariantField("payload", 1, "proto",
+				&Case{Name: "icmp", Value: 1, Template: icmp},
+			))
+		}).To(Panic())
+		Expect(func() {
+			NewTemplate(-1, StringField("proto", 0, 1, 0), VariantField("payload", 1, "proto",
+				&Case{Name: "icmp", Value: 1, Template: icmp},
+			))
+		}).To(Panic())
+		Expect(func() {
+			NewTemplate(-1, BigUintField("proto", 0, 16), VariantField("payload", 16, "proto",
+				&Case{Name: "icmp", Value: 1, Template: icmp},
+			))
+		}).To(Panic())
+		Expect(func() {
+			NewTemplate(-1, &Field{Name: "proto", Len: 16, Kind: Uint64Kind}, VariantField("payload", 16, "proto",
+				&Case{Name: "icmp", Value: 1, Template: icmp},
+			))
+		}).To(Panic())
+		Expect(func() {
+			s.Variant("proto")
+		}).To(Panic())
+	})
+})